@@ -0,0 +1,76 @@
+package rtml
+
+import "testing"
+
+// prevent the compiler from optimizing away the benchmarked calls.
+var (
+	benchReached bool
+	benchStats   MemLimitRelatedStats
+)
+
+func BenchmarkIsMemLimitReached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchReached = IsMemLimitReached()
+	}
+}
+
+// measures contention on the runtime atomics when many goroutines check the limit concurrently.
+func BenchmarkIsMemLimitReachedParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var reached bool
+		for pb.Next() {
+			reached = IsMemLimitReached()
+		}
+		benchReached = reached
+	})
+}
+
+func BenchmarkGetMemLimitRelatedStats(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchStats = GetMemLimitRelatedStats()
+	}
+}
+
+// the checks made when mapped memory is at or above the limit, compared with the fast path of BenchmarkIsMemLimitReached.
+// a 1 byte limit is passed directly instead of lowering the runtime limit, which would make the collector run continuously.
+func BenchmarkIsMemLimitReachedSlowPath(b *testing.B) {
+	mappedReady := runtimeGCController.mappedReady.Load()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchReached = isMemLimitReachedSlow(1, mappedReady)
+	}
+}
+
+// a guard for the hot path that doesn't depend on the timing of the machine.
+func TestIsMemLimitReachedDoesNotAllocate(t *testing.T) {
+	mappedReady := runtimeGCController.mappedReady.Load()
+	checks := map[string]func(){
+		"IsMemLimitReached":       func() { benchReached = IsMemLimitReached() },
+		"isMemLimitReachedSlow":   func() { benchReached = isMemLimitReachedSlow(1, mappedReady) },
+		"MemLimitStatus":          func() { benchReached, _ = MemLimitStatus() },
+		"GetMemLimitRelatedStats": func() { benchStats = GetMemLimitRelatedStats() },
+	}
+	for name, fn := range checks {
+		if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+			t.Errorf("%s allocates %v times per call, want 0", name, allocs)
+		}
+	}
+}
+
+// fills the same struct on every call, which must not allocate.
+func BenchmarkGetMemLimitRelatedStatsInto(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetMemLimitRelatedStatsInto(&benchStats)
+	}
+}
+
+func TestGetMemLimitRelatedStatsIntoDoesNotAllocate(t *testing.T) {
+	var stats MemLimitRelatedStats
+	if allocs := testing.AllocsPerRun(100, func() { GetMemLimitRelatedStatsInto(&stats) }); allocs != 0 {
+		t.Errorf("GetMemLimitRelatedStatsInto allocates %v times per call, want 0", allocs)
+	}
+}
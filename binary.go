@@ -0,0 +1,62 @@
+package rtml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// version of the binary encoding of MemLimitRelatedStats, the first byte of the encoded form.
+// new fields must be appended with a new version, so older snapshots can still be decoded.
+const statsBinaryVersion = 1
+
+// number of fields in the binary encoding.
+const statsBinaryFieldCount = 9
+
+// size of the binary encoding: the version byte, followed by the fields as little endian uint64.
+const statsBinarySize = 1 + statsBinaryFieldCount*8
+
+// returned by MemLimitRelatedStats.UnmarshalBinary for data that is not an encoded snapshot.
+var ErrInvalidStatsEncoding = errors.New("rtml: invalid binary encoding of MemLimitRelatedStats")
+
+// MarshalBinary implements encoding.BinaryMarshaler, with a compact fixed size layout
+// (a version byte, then every field as little endian uint64 in declaration order),
+// for agents that ship snapshots in dense binary frames instead of JSON.
+func (s MemLimitRelatedStats) MarshalBinary() ([]byte, error) {
+	return s.AppendBinary(make([]byte, 0, statsBinarySize))
+}
+
+// AppendBinary appends the binary encoding of MarshalBinary to b, so frames can be built without extra copies.
+func (s MemLimitRelatedStats) AppendBinary(b []byte) ([]byte, error) {
+	b = append(b, statsBinaryVersion)
+	for _, v := range s.binaryFields() {
+		b = binary.LittleEndian.AppendUint64(b, *v)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the output of MarshalBinary.
+// It returns an error wrapping ErrInvalidStatsEncoding if data has the wrong size or an unknown version.
+func (s *MemLimitRelatedStats) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty data", ErrInvalidStatsEncoding)
+	}
+	if version := data[0]; version != statsBinaryVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidStatsEncoding, version)
+	}
+	if len(data) != statsBinarySize {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidStatsEncoding, statsBinarySize, len(data))
+	}
+
+	var decoded MemLimitRelatedStats
+	for i, v := range decoded.binaryFields() {
+		*v = binary.LittleEndian.Uint64(data[1+i*8:])
+	}
+	*s = decoded
+	return nil
+}
+
+// the fields in their encoding order.
+func (s *MemLimitRelatedStats) binaryFields() [statsBinaryFieldCount]*uint64 {
+	return [statsBinaryFieldCount]*uint64{&s.MemoryLimit, &s.HeapGoal, &s.HeapLive, &s.MappedReady, &s.HeapFree, &s.HeapReleased, &s.HeapInUse, &s.TotalAlloc, &s.TotalFree}
+}
@@ -0,0 +1,57 @@
+package rtml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStatsBinaryRoundTrip(t *testing.T) {
+	stats := MemLimitRelatedStats{
+		MemoryLimit:  1,
+		HeapGoal:     2,
+		HeapLive:     3,
+		MappedReady:  4,
+		HeapFree:     5,
+		HeapReleased: 6,
+		HeapInUse:    7,
+		TotalAlloc:   8,
+		TotalFree:    1 << 63,
+	}
+
+	encoded, err := stats.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() = %v", err)
+	}
+	if len(encoded) != statsBinarySize || encoded[0] != 1 {
+		t.Errorf("MarshalBinary() = %d bytes of version %d, want %d bytes of version 1", len(encoded), encoded[0], statsBinarySize)
+	}
+
+	var decoded MemLimitRelatedStats
+	if err := decoded.UnmarshalBinary(encoded); err != nil {
+		t.Fatalf("UnmarshalBinary() = %v", err)
+	}
+	if decoded != stats {
+		t.Errorf("round trip = %s, want %s", decoded, stats)
+	}
+}
+
+func TestStatsUnmarshalBinaryInvalid(t *testing.T) {
+	valid, _ := MemLimitRelatedStats{}.MarshalBinary()
+	unknownVersion := append([]byte{}, valid...)
+	unknownVersion[0] = 0xff
+
+	tests := map[string][]byte{
+		"empty":           nil,
+		"truncated":       valid[:len(valid)-1],
+		"trailing":        append(append([]byte{}, valid...), 0),
+		"unknown version": unknownVersion,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s MemLimitRelatedStats
+			if err := s.UnmarshalBinary(data); !errors.Is(err, ErrInvalidStatsEncoding) {
+				t.Errorf("UnmarshalBinary() = %v, want ErrInvalidStatsEncoding", err)
+			}
+		})
+	}
+}
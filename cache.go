@@ -0,0 +1,70 @@
+package rtml
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CachedStats serves MemLimitRelatedStats snapshots that are refreshed at most once per ttl.
+//
+// It is meant for dashboards and exporters that call GetMemLimitRelatedStats from many goroutines
+// at once, where re-reading all the runtime values on every call is wasted work.
+// The tradeoff is staleness: a returned snapshot can be up to ttl old (plus the duration of a refresh).
+//
+// The read path is lock free (a single atomic pointer load). When the snapshot expires,
+// the caller that notices it starts a single background refresh, and all callers keep getting the previous
+// snapshot until it completes, so no caller pays for reading the runtime values
+// (except the very first ones, when nothing is cached yet).
+type CachedStats struct {
+	source     StatsSource
+	ttl        time.Duration
+	snapshot   atomic.Pointer[cachedSnapshot]
+	refreshing atomic.Bool
+}
+
+type cachedSnapshot struct {
+	stats  MemLimitRelatedStats
+	readAt time.Time
+}
+
+// NewCachedStats creates a cache that refreshes the stats at most once per ttl.
+// A ttl of 0 uses the ttl set with WithCacheTTL (1 second by default).
+func NewCachedStats(ttl time.Duration) *CachedStats {
+	return NewCachedStatsFor(ttl, runtimeStatsSource{})
+}
+
+// NewCachedStatsFor creates a cache of the stats of source instead of the go runtime. See StatsSource.
+func NewCachedStatsFor(ttl time.Duration, source StatsSource) *CachedStats {
+	if ttl <= 0 {
+		ttl = time.Duration(configuredCacheTTL.Load())
+	}
+	return &CachedStats{source: sourceOrRuntime(source), ttl: ttl}
+}
+
+// Get returns the cached snapshot. When it is older than ttl, it starts refreshing it in the background
+// and returns it anyway, so the snapshot can be up to ttl plus the duration of a refresh old.
+// The first call, when nothing is cached yet, reads the stats itself.
+func (c *CachedStats) Get() MemLimitRelatedStats {
+	snapshot := c.snapshot.Load()
+	if snapshot == nil {
+		// nothing cached yet, read directly (first calls racing may all read).
+		return c.refresh()
+	}
+	if time.Since(snapshot.readAt) >= c.ttl && c.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer c.refreshing.Store(false)
+			c.refresh()
+		}()
+	}
+	return snapshot.stats
+}
+
+// reads the stats from the source and caches them.
+func (c *CachedStats) refresh() MemLimitRelatedStats {
+	fresh := &cachedSnapshot{
+		stats:  c.source.Stats(),
+		readAt: time.Now(),
+	}
+	c.snapshot.Store(fresh)
+	return fresh.stats
+}
@@ -0,0 +1,58 @@
+package rtml
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a StatsSource counting its reads, which block while gate is not closed.
+type countingSource struct {
+	reads atomic.Int64
+	gate  chan struct{}
+}
+
+func (s *countingSource) Stats() MemLimitRelatedStats {
+	n := s.reads.Add(1)
+	<-s.gate
+	return MemLimitRelatedStats{TotalAlloc: uint64(n)}
+}
+
+func (s *countingSource) Reached() bool { return false }
+
+func TestCachedStatsRefreshesOnceInBackground(t *testing.T) {
+	source := &countingSource{gate: make(chan struct{})}
+	close(source.gate)
+	cache := NewCachedStatsFor(10*time.Millisecond, source)
+	if stats := cache.Get(); stats.TotalAlloc != 1 {
+		t.Fatalf("first Get() = %d, want the first read", stats.TotalAlloc)
+	}
+
+	// expire the snapshot, and hold the refresh until every concurrent Get returned.
+	source.gate = make(chan struct{})
+	time.Sleep(20 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if stats := cache.Get(); stats.TotalAlloc != 1 {
+				t.Errorf("Get() = %d while refreshing, want the stale snapshot", stats.TotalAlloc)
+			}
+		}()
+	}
+	wg.Wait()
+	close(source.gate)
+
+	deadline := time.Now().Add(time.Second)
+	for cache.snapshot.Load().stats.TotalAlloc == 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if reads := source.reads.Load(); reads != 2 {
+		t.Errorf("source read %d times, want exactly one refresh", reads)
+	}
+	if stats := cache.Get(); stats.TotalAlloc != 2 {
+		t.Errorf("Get() = %d after the refresh, want the refreshed snapshot", stats.TotalAlloc)
+	}
+}
@@ -0,0 +1,186 @@
+package rtml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// returned when neither cgroup v2 nor cgroup v1 memory controller files can be found,
+	// for example when not running on linux or not inside a container.
+	ErrCgroupNotFound = errors.New("rtml: cgroup memory controller not found")
+
+	// returned when the cgroup exists but has no memory limit configured ("max" in cgroup v2).
+	ErrNoCgroupMemoryLimit = errors.New("rtml: cgroup has no memory limit")
+
+	// returned for values that are only available on cgroup v2, when running on cgroup v1.
+	ErrCgroupV1Unsupported = errors.New("rtml: not supported on cgroup v1")
+)
+
+// mount point of the cgroup filesystem.
+// inside a container (with cgroup namespace) this is the container's own cgroup.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroup v1 reports "no limit" as a very large number (page aligned max int64),
+// anything above this value is treated as unlimited.
+const cgroupV1UnlimitedThreshold = 1 << 62
+
+// reads a memory controller file, trying the cgroup v2 file name first,
+// and falling back to the cgroup v1 file name under the "memory" hierarchy.
+// v1Name can be empty if the value has no cgroup v1 equivalent.
+func readCgroupMemoryFile(v2Name, v1Name string) (value string, v2 bool, err error) {
+	data, err := os.ReadFile(filepath.Join(cgroupRoot, v2Name))
+	if err == nil {
+		return strings.TrimSpace(string(data)), true, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", true, err
+	}
+
+	if v1Name != "" {
+		data, err = os.ReadFile(filepath.Join(cgroupRoot, "memory", v1Name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), false, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", false, err
+		}
+	}
+
+	return "", false, ErrCgroupNotFound
+}
+
+// CgroupMemoryMax returns the hard memory limit in bytes of the cgroup (container) the process runs in.
+//
+// It reads memory.max on cgroup v2, or memory/memory.limit_in_bytes on cgroup v1.
+// When the cgroup has no limit ("max" in v2, or the huge sentinel value in v1), ErrNoCgroupMemoryLimit is returned.
+//
+// The value can be compared with the runtime memory limit (GOMEMLIMIT) to detect
+// misconfigurations where GOMEMLIMIT is not derived from the container limit.
+func CgroupMemoryMax() (uint64, error) {
+	value, _, err := readCgroupMemoryFile("memory.max", "memory.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	return parseCgroupLimit(value)
+}
+
+// CgroupMemoryHigh returns the memory.high soft limit in bytes of the cgroup (container) the process runs in.
+//
+// Unlike memory.max (see CgroupMemoryMax), going over memory.high does not OOM kill the process: the kernel
+// throttles it and forces it into reclaim, which shows up as latency stalls. Setting GOMEMLIMIT below memory.high
+// avoids those stalls, while memory.max stays the hard limit that kills.
+//
+// It is only available on cgroup v2, ErrCgroupV1Unsupported is returned on cgroup v1.
+// When memory.high is not set ("max"), ErrNoCgroupMemoryLimit is returned.
+func CgroupMemoryHigh() (uint64, error) {
+	value, _, err := readCgroupMemoryFile("memory.high", "")
+	if errors.Is(err, ErrCgroupNotFound) {
+		if _, statErr := os.Stat(filepath.Join(cgroupRoot, "memory")); statErr == nil {
+			return 0, ErrCgroupV1Unsupported
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseCgroupLimit(value)
+}
+
+// parses a limit value from a cgroup file, handling the "no limit" sentinels of both versions.
+func parseCgroupLimit(value string) (uint64, error) {
+	if value == "max" {
+		return 0, ErrNoCgroupMemoryLimit
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rtml: failed to parse cgroup memory limit %q: %w", value, err)
+	}
+	if limit >= cgroupV1UnlimitedThreshold {
+		return 0, ErrNoCgroupMemoryLimit
+	}
+	return limit, nil
+}
+
+// CgroupMemoryCurrent returns the memory in bytes currently charged to the cgroup (container)
+// the process runs in, as seen by the kernel.
+//
+// It reads memory.current on cgroup v2, or memory/memory.usage_in_bytes on cgroup v1.
+// The kernel value includes page cache and memory not managed by the go runtime,
+// so it can be used to sanity check how well MappedReady reflects the real container usage.
+func CgroupMemoryCurrent() (uint64, error) {
+	value, _, err := readCgroupMemoryFile("memory.current", "memory.usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	current, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rtml: failed to parse cgroup memory usage %q: %w", value, err)
+	}
+	return current, nil
+}
+
+// counters from the cgroup v2 memory.events file.
+// each field is the number of times the event occurred since the cgroup was created.
+type MemoryEvents struct {
+	// times the cgroup was reclaimed from while below its memory.low protection.
+	Low uint64
+
+	// times the cgroup usage went over memory.high and was throttled and forced into reclaim.
+	High uint64
+
+	// times the cgroup usage was about to go over memory.max.
+	Max uint64
+
+	// times the cgroup hit the limit and allocations failed after reclaim.
+	OOM uint64
+
+	// number of processes in the cgroup killed by the OOM killer.
+	OOMKill uint64
+}
+
+// CgroupMemoryEvents returns the kernel memory pressure event counters of the cgroup (container),
+// parsed from memory.events.
+//
+// Comparing those counters over time with the IsMemLimitReached results shows how well
+// the heuristic tracks real kernel pressure (throttling and OOM kills).
+// It is only available on cgroup v2, ErrCgroupV1Unsupported is returned on cgroup v1.
+func CgroupMemoryEvents() (MemoryEvents, error) {
+	value, _, err := readCgroupMemoryFile("memory.events", "")
+	if errors.Is(err, ErrCgroupNotFound) {
+		if _, statErr := os.Stat(filepath.Join(cgroupRoot, "memory")); statErr == nil {
+			return MemoryEvents{}, ErrCgroupV1Unsupported
+		}
+	}
+	if err != nil {
+		return MemoryEvents{}, err
+	}
+
+	var events MemoryEvents
+	for _, line := range strings.Split(value, "\n") {
+		key, count, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(count), 10, 64)
+		if err != nil {
+			return MemoryEvents{}, fmt.Errorf("rtml: failed to parse memory.events line %q: %w", line, err)
+		}
+		switch key {
+		case "low":
+			events.Low = n
+		case "high":
+			events.High = n
+		case "max":
+			events.Max = n
+		case "oom":
+			events.OOM = n
+		case "oom_kill":
+			events.OOMKill = n
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,128 @@
+package rtml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// points cgroupRoot to a fake cgroupfs in a temporary directory, holding files (relative path => content).
+func setCgroupRootForTest(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = previous })
+}
+
+func TestCgroupMemoryMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    uint64
+		wantErr error
+	}{
+		{name: "v2 numeric", files: map[string]string{"memory.max": "536870912\n"}, want: 536870912},
+		{name: "v2 max", files: map[string]string{"memory.max": "max\n"}, wantErr: ErrNoCgroupMemoryLimit},
+		{name: "v1 numeric", files: map[string]string{"memory/memory.limit_in_bytes": "268435456\n"}, want: 268435456},
+		{name: "v1 unlimited", files: map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"}, wantErr: ErrNoCgroupMemoryLimit},
+		{name: "v2 preferred over v1", files: map[string]string{"memory.max": "100", "memory/memory.limit_in_bytes": "200"}, want: 100},
+		{name: "missing", files: nil, wantErr: ErrCgroupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCgroupRootForTest(t, tt.files)
+			got, err := CgroupMemoryMax()
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("CgroupMemoryMax() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCgroupMemoryMaxInvalid(t *testing.T) {
+	setCgroupRootForTest(t, map[string]string{"memory.max": "lots"})
+	if _, err := CgroupMemoryMax(); err == nil || errors.Is(err, ErrNoCgroupMemoryLimit) {
+		t.Errorf("CgroupMemoryMax() error = %v, want a parse error", err)
+	}
+}
+
+func TestCgroupMemoryCurrent(t *testing.T) {
+	setCgroupRootForTest(t, map[string]string{"memory/memory.usage_in_bytes": "4096\n"})
+	if got, err := CgroupMemoryCurrent(); err != nil || got != 4096 {
+		t.Errorf("CgroupMemoryCurrent() = %d, %v, want 4096, nil", got, err)
+	}
+}
+
+func TestCgroupMemoryEvents(t *testing.T) {
+	setCgroupRootForTest(t, map[string]string{"memory.events": "low 1\nhigh 22\nmax 3\noom 4\noom_kill 5\n"})
+	want := MemoryEvents{Low: 1, High: 22, Max: 3, OOM: 4, OOMKill: 5}
+	if got, err := CgroupMemoryEvents(); err != nil || got != want {
+		t.Errorf("CgroupMemoryEvents() = %+v, %v, want %+v, nil", got, err, want)
+	}
+
+	setCgroupRootForTest(t, map[string]string{"memory/memory.limit_in_bytes": "100"})
+	if _, err := CgroupMemoryEvents(); !errors.Is(err, ErrCgroupV1Unsupported) {
+		t.Errorf("CgroupMemoryEvents() error = %v on cgroup v1, want %v", err, ErrCgroupV1Unsupported)
+	}
+
+	setCgroupRootForTest(t, nil)
+	if _, err := CgroupMemoryEvents(); !errors.Is(err, ErrCgroupNotFound) {
+		t.Errorf("CgroupMemoryEvents() error = %v without a cgroup, want %v", err, ErrCgroupNotFound)
+	}
+}
+
+func TestCgroupMemoryHigh(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    uint64
+		wantErr error
+	}{
+		{name: "set", files: map[string]string{"memory.high": "400\n"}, want: 400},
+		{name: "max", files: map[string]string{"memory.high": "max\n"}, wantErr: ErrNoCgroupMemoryLimit},
+		{name: "v1", files: map[string]string{"memory/memory.limit_in_bytes": "100"}, wantErr: ErrCgroupV1Unsupported},
+		{name: "missing", files: nil, wantErr: ErrCgroupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCgroupRootForTest(t, tt.files)
+			got, err := CgroupMemoryHigh()
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("CgroupMemoryHigh() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCgroupTargetLimitPreferHigh(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  uint64
+	}{
+		{name: "high below max", files: map[string]string{"memory.high": "400", "memory.max": "500"}, want: 400},
+		{name: "high above max", files: map[string]string{"memory.high": "600", "memory.max": "500"}, want: 500},
+		{name: "high not set", files: map[string]string{"memory.high": "max", "memory.max": "500"}, want: 500},
+		{name: "v1", files: map[string]string{"memory/memory.limit_in_bytes": "300"}, want: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCgroupRootForTest(t, tt.files)
+			got, err := cgroupTargetLimit(cgroupLimitConfig{preferHigh: true})
+			if err != nil || got != tt.want {
+				t.Errorf("cgroupTargetLimit() = %d, %v, want %d, nil", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package rtml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// returned by Configure when it was already called.
+var ErrAlreadyConfigured = errors.New("rtml: Configure was already called")
+
+// package wide settings, applied by Configure.
+type config struct {
+	headroomFraction float64
+	overshootCap     float64
+	lowWatermark     float64
+	conservative     bool
+	cacheTTL         time.Duration
+}
+
+// Option configures the package defaults, see Configure.
+type Option func(*config) error
+
+// WithHeadroomFraction reserves a fraction of the memory limit (between 0 and 1),
+// so IsMemLimitReached returns true once the memory in use crosses limit * (1 - fraction),
+// before the runtime itself considers the limit reached.
+// It makes room for allocations that are already in flight when work stops being accepted.
+// While a headroom is reserved, the memory in use crossing it is enough: the heap goal check is skipped,
+// and the limit stays reached above the limit itself as well.
+// The default is 0 (no headroom).
+func WithHeadroomFraction(fraction float64) Option {
+	return func(c *config) error {
+		if !(fraction >= 0 && fraction < 1) {
+			return fmt.Errorf("rtml: invalid headroom fraction %v, expected 0 <= fraction < 1", fraction)
+		}
+		c.headroomFraction = fraction
+		return nil
+	}
+}
+
+// WithOvershootHeadroom reserves the worst heap goal overshoot observed so far (see GCOvershootBytes)
+// below the memory limit, on top of WithHeadroomFraction, so the safety margin follows the GC behavior of the workload.
+// The reserved overshoot is capped at maxFraction of the memory limit (between 0 and 1, exclusive):
+// while the limit itself is reached the live heap stays above the goal, and without a cap a single episode
+// would make admission permanently over conservative.
+// The default is to track the overshoot without reserving it.
+func WithOvershootHeadroom(maxFraction float64) Option {
+	return func(c *config) error {
+		if !(maxFraction > 0 && maxFraction < 1) {
+			return fmt.Errorf("rtml: invalid overshoot headroom cap %v, expected 0 < fraction < 1", maxFraction)
+		}
+		c.overshootCap = maxFraction
+		return nil
+	}
+}
+
+// WithLowWatermark sets the utilization ratio at which pressure starts:
+// GetMemoryPressureLevel reports PressureLevelWarning from this ratio, and ShouldReject
+// starts rejecting work (the low of SetRejectWatermarks). The default is 0.8.
+func WithLowWatermark(ratio float64) Option {
+	return func(c *config) error {
+		if !(ratio >= 0 && ratio < 1) {
+			return fmt.Errorf("rtml: invalid low watermark %v, expected 0 <= ratio < 1", ratio)
+		}
+		c.lowWatermark = ratio
+		return nil
+	}
+}
+
+// WithConservative sets the fallback under ambiguous conditions, see SetConservative.
+// The default is false (fail open).
+func WithConservative(enabled bool) Option {
+	return func(c *config) error {
+		c.conservative = enabled
+		return nil
+	}
+}
+
+// WithCacheTTL sets the ttl of caches created with NewCachedStats(0). The default is 1 second.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *config) error {
+		if ttl <= 0 {
+			return fmt.Errorf("rtml: invalid cache ttl %v, expected a positive duration", ttl)
+		}
+		c.cacheTTL = ttl
+		return nil
+	}
+}
+
+const (
+	defaultLowWatermark = 0.8
+	defaultCacheTTL     = time.Second
+)
+
+var (
+	configureMu sync.Mutex
+	configured  bool
+
+	// the reserved part of the memory limit, in parts per million.
+	// an integer, so the fast path of IsMemLimitReached applies it without floating point math.
+	headroomPPM atomic.Uint64
+
+	// float64 bits of the utilization ratio at which PressureLevelWarning starts.
+	warningRatioBits atomic.Uint64
+
+	// ttl of caches created with NewCachedStats(0).
+	configuredCacheTTL atomic.Int64
+)
+
+func init() {
+	warningRatioBits.Store(math.Float64bits(defaultLowWatermark))
+	configuredCacheTTL.Store(int64(defaultCacheTTL))
+}
+
+// Configure sets the package defaults from options, instead of passing parameters to every call:
+//
+//	err := rtml.Configure(
+//		rtml.WithHeadroomFraction(0.05),
+//		rtml.WithLowWatermark(0.7),
+//		rtml.WithConservative(true),
+//	)
+//
+// Call it once at startup, before the package is used. Only the first call takes effect,
+// later calls are ignored and return ErrAlreadyConfigured. If any option is invalid,
+// nothing is applied, the error is returned, and Configure can be called again.
+// Options that are not passed keep their defaults.
+//
+// The settings are stored atomically, so it is safe even if other goroutines already use the package.
+func Configure(opts ...Option) error {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	if configured {
+		return ErrAlreadyConfigured
+	}
+
+	c := config{
+		lowWatermark: defaultLowWatermark,
+		cacheTTL:     defaultCacheTTL,
+	}
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return err
+		}
+	}
+
+	high := currentRejectWatermarks.Load().high
+	if err := SetRejectWatermarks(c.lowWatermark, high); err != nil {
+		return err
+	}
+	headroomPPM.Store(uint64(c.headroomFraction * 1_000_000))
+	overshootCapPPM.Store(uint64(c.overshootCap * 1_000_000))
+	if c.overshootCap > 0 {
+		startOvershootTracker()
+	}
+	warningRatioBits.Store(math.Float64bits(c.lowWatermark))
+	SetConservative(c.conservative)
+	configuredCacheTTL.Store(int64(c.cacheTTL))
+
+	configured = true
+	return nil
+}
+
+// the memory limit after deducting the configured headroom, and the learned overshoot (see WithOvershootHeadroom).
+func effectiveMemoryLimit(memoryLimit uint64) uint64 {
+	limit := memoryLimit
+	if ppm := headroomPPM.Load(); ppm != 0 {
+		limit -= memoryLimit / 1_000_000 * ppm
+	}
+	return limit - overshootHeadroom(memoryLimit, limit)
+}
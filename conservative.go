@@ -0,0 +1,36 @@
+package rtml
+
+import "sync/atomic"
+
+// when set, ambiguous conditions are treated as "limit reached" (fail closed).
+var conservative atomic.Bool
+
+// SetConservative controls what IsMemLimitReached returns when the heuristic can't decide.
+//
+// By default (false) it fails open: ambiguous conditions return "false", and work keeps being accepted.
+// When set to true it fails closed: ambiguous conditions return "true", and work is rejected.
+// Choose fail closed when an OOM kill costs more than rejecting work, e.g. when the senders retry.
+//
+// The conditions considered ambiguous are, when the mapped ready memory is at or above the memory limit:
+//   - the runtime internals can't be trusted (ReasonDegraded): LayoutVerified returned an error,
+//     or reading the runtime internals panicked.
+//   - the heap free bytes are larger than the mapped ready bytes, which contain them (ReasonInconsistent).
+//   - the live heap is 0 after the controller warmed up (ReasonInconsistent).
+//     Before it warmed up (see ControllerReady), the result is always "false" (ReasonWarmingUp).
+//
+// While the mapped ready memory is below the limit, the fast path answers "false" regardless of this setting.
+// It is safe to call concurrently with IsMemLimitReached.
+func SetConservative(enabled bool) {
+	conservative.Store(enabled)
+}
+
+// reports whether a reason returned by the slow path means the limit is reached.
+func reachedForReason(reason string) bool {
+	switch reason {
+	case ReasonAboveGoal, ReasonHeadroom, ReasonGoalUnavailable:
+		return true
+	case ReasonDegraded, ReasonInconsistent:
+		return conservative.Load()
+	}
+	return false
+}
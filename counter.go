@@ -0,0 +1,44 @@
+package rtml
+
+// Counter measures the allocations and frees of a window, from the monotonic TotalAlloc and TotalFree counters,
+// e.g. around a job: take a Baseline when it starts, and read Since when it ends.
+//
+// The counters are process wide: the window includes everything the process allocated and freed meanwhile,
+// not only the work of the caller, so it is exact for a job that runs alone and an upper bound otherwise.
+// The zero value measures since the process started. A Counter is not safe for concurrent Baseline calls.
+type Counter struct {
+	totalAlloc uint64
+	totalFree  uint64
+}
+
+// NewCounter returns a counter with its baseline set to the current counters.
+func NewCounter() *Counter {
+	c := &Counter{}
+	c.Baseline()
+	return c
+}
+
+// Baseline starts a new window from the current TotalAlloc and TotalFree.
+func (c *Counter) Baseline() {
+	c.totalAlloc, c.totalFree = readAllocCounters()
+}
+
+// Since returns the bytes allocated and freed since the baseline, in span resolution like TotalAlloc and TotalFree.
+func (c *Counter) Since() (allocated, freed uint64) {
+	totalAlloc, totalFree := readAllocCounters()
+	return counterDelta(totalAlloc, c.totalAlloc), counterDelta(totalFree, c.totalFree)
+}
+
+// the monotonic counters, or zeros if reading them panics.
+func readAllocCounters() (totalAlloc, totalFree uint64) {
+	defer markDegradedOnPanic()
+	return runtimeGCController.totalAlloc.Load(), runtimeGCController.totalFree.Load()
+}
+
+// the growth of a monotonic counter since baseline, or 0 if it reads lower (e.g. zeros after a failed read).
+func counterDelta(current, baseline uint64) uint64 {
+	if current < baseline {
+		return 0
+	}
+	return current - baseline
+}
@@ -0,0 +1,139 @@
+package rtml
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// String formats the stats in a compact, human readable form, e.g.
+//
+//	MemoryLimit=512.00MiB HeapGoal=72.00MiB HeapLive=50.01MiB ...
+func (s MemLimitRelatedStats) String() string {
+	var sb strings.Builder
+	for i, f := range s.namedValues() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.name)
+		sb.WriteByte('=')
+		sb.WriteString(formatBytes(f.value))
+	}
+	return sb.String()
+}
+
+type namedValue struct {
+	name  string
+	value uint64
+}
+
+// the stats fields in their declaration order, shared by all the human readable formats.
+func (s MemLimitRelatedStats) namedValues() []namedValue {
+	values := make([]namedValue, len(statFields))
+	for i, f := range statFields {
+		values[i] = namedValue{f.goName, f.value(s)}
+	}
+	return values
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+type debugInfo struct {
+	Stats         MemLimitRelatedStats `json:"stats"`
+	PressureLevel string               `json:"pressure_level"`
+	Utilization   float64              `json:"utilization"`
+	Summary       string               `json:"summary"`
+}
+
+var debugTemplate = template.Must(template.New("rtml").Parse(`<!DOCTYPE html>
+<html>
+<head><title>rtml</title></head>
+<body>
+<h1>go-rtml memory limit stats</h1>
+<p>Pressure level: <b>{{.PressureLevel}}</b>, utilization: <b>{{printf "%.1f" .UtilizationPercent}}%</b></p>
+<table border="1" cellpadding="4">
+<tr><th>Field</th><th>Value</th><th>Bytes</th></tr>
+{{range .Fields}}<tr><td>{{.Name}}</td><td>{{.Formatted}}</td><td>{{.Bytes}}</td></tr>
+{{end}}</table>
+<pre>{{.Summary}}</pre>
+</body>
+</html>
+`))
+
+type debugHTMLField struct {
+	Name      string
+	Formatted string
+	Bytes     uint64
+}
+
+// DebugHandler returns an http.Handler that renders the current stats,
+// pressure level and utilization ratio, in the spirit of net/http/pprof.
+//
+// The response is JSON when the request "Accept" header asks for "application/json",
+// and a simple HTML page otherwise.
+// The handler does not depend on the request path, so it can be mounted on
+// any prefix of an existing debug mux:
+//
+//	mux.Handle("/debug/rtml", rtml.DebugHandler())
+func DebugHandler() http.Handler {
+	return DebugHandlerFor(runtimeStatsSource{})
+}
+
+// DebugHandlerFor is DebugHandler, rendering the stats of source instead of the go runtime. See StatsSource.
+func DebugHandlerFor(source StatsSource) http.Handler {
+	source = sourceOrRuntime(source)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		stats := source.Stats()
+		info := debugInfo{
+			Stats:         stats,
+			PressureLevel: pressureLevelOf(source).String(),
+			Utilization:   utilizationRatio(stats.MemoryLimit, stats.MappedReady, stats.HeapFree),
+			Summary:       stats.String(),
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(info)
+			return
+		}
+
+		fields := make([]debugHTMLField, 0, 7)
+		for _, f := range stats.namedValues() {
+			fields = append(fields, debugHTMLField{Name: f.name, Formatted: formatBytes(f.value), Bytes: f.value})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = debugTemplate.Execute(w, struct {
+			PressureLevel      string
+			UtilizationPercent float64
+			Fields             []debugHTMLField
+			Summary            string
+		}{
+			PressureLevel:      info.PressureLevel,
+			UtilizationPercent: info.Utilization * 100,
+			Fields:             fields,
+			Summary:            info.Summary,
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package rtml
+
+// RSSDivergenceWarningRatio is the RSSDivergence ratio above which memory not managed by the go heap
+// (cgo, mmap, page cache, goroutine stacks, etc.) is considered significant: from there, the container
+// can be OOM killed while IsMemLimitReached still reports the limit as not reached.
+const RSSDivergenceWarningRatio = 1.5
+
+// RSSDivergence compares the memory the go runtime accounts for (MappedReady, what the heuristic is based on)
+// with the memory the kernel charges to the cgroup (container), which is what the OOM killer acts on.
+//
+// ratio is cgroupCurrent / heapMapped. It is usually slightly above 1 (binary text, runtime metadata).
+// A ratio above RSSDivergenceWarningRatio means non-heap memory dominates, and the rtml heuristic may be
+// too optimistic: consider a larger reserve in AutoSetMemoryLimitFromCgroup, or a lower GOMEMLIMIT.
+// This is common for cgo-heavy workloads, and for processes with a large page cache (e.g. heavy file IO).
+//
+// The error of CgroupMemoryCurrent is returned when the cgroup can't be read (e.g. not on linux).
+// ratio is 0 when heapMapped is 0.
+func RSSDivergence() (heapMapped, cgroupCurrent uint64, ratio float64, err error) {
+	cgroupCurrent, err = CgroupMemoryCurrent()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	heapMapped = runtimeGCController.mappedReady.Load()
+	if heapMapped == 0 {
+		return 0, cgroupCurrent, 0, nil
+	}
+	return heapMapped, cgroupCurrent, float64(cgroupCurrent) / float64(heapMapped), nil
+}
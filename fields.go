@@ -0,0 +1,97 @@
+package rtml
+
+// StatField describes a MemLimitRelatedStats field for metrics exporters,
+// so they can generate metric names, types and help texts from a single source of truth.
+type StatField struct {
+	// snake case name of the field, e.g. "heap_live", used as the metric name by the exporters.
+	Name string
+
+	// unit of the value, e.g. "bytes".
+	Unit string
+
+	// one line description, suitable as the help text of a metric.
+	Description string
+
+	// true for monotonic counters (e.g. total_alloc), which only ever increase,
+	// and false for gauges, which go up and down.
+	IsCounter bool
+
+	// the field name in MemLimitRelatedStats, e.g. "HeapLive".
+	goName string
+	value  func(MemLimitRelatedStats) uint64
+
+	// the field holds the math.MaxInt64 sentinel when no memory limit is set, and is not reported then.
+	skipWhenUnlimited bool
+}
+
+// Value returns the value of the field in s.
+func (f StatField) Value(s MemLimitRelatedStats) uint64 {
+	return f.value(s)
+}
+
+// IsSet reports whether the field has a meaningful value in s. It is false for memory_limit when
+// no memory limit is set (the runtime reports math.MaxInt64), and exporters skip the field then.
+func (f StatField) IsSet(s MemLimitRelatedStats) bool {
+	return !f.skipWhenUnlimited || f.value(s) != noMemoryLimit
+}
+
+// the MemLimitRelatedStats fields in their declaration order.
+var statFields = []StatField{
+	{
+		Name: "memory_limit", Unit: "bytes", goName: "MemoryLimit", skipWhenUnlimited: true,
+		Description: "The runtime memory limit (GOMEMLIMIT).",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.MemoryLimit },
+	},
+	{
+		Name: "heap_goal", Unit: "bytes", goName: "HeapGoal",
+		Description: "The heap size at which the garbage collector aims to finish the next cycle.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.HeapGoal },
+	},
+	{
+		Name: "heap_live", Unit: "bytes", goName: "HeapLive",
+		Description: "The live heap size, in span resolution, including objects not collected yet.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.HeapLive },
+	},
+	{
+		Name: "mapped_ready", Unit: "bytes", goName: "MappedReady",
+		Description: "The memory the runtime counts towards the memory limit.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.MappedReady },
+	},
+	{
+		Name: "heap_free", Unit: "bytes", goName: "HeapFree",
+		Description: "The ready memory not used by the heap, available for new allocations.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.HeapFree },
+	},
+	{
+		Name: "heap_released", Unit: "bytes", goName: "HeapReleased",
+		Description: "The heap memory returned to the OS, still mapped but not resident.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.HeapReleased },
+	},
+	{
+		Name: "heap_in_use", Unit: "bytes", goName: "HeapInUse",
+		Description: "The memory of the heap spans currently holding objects.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.HeapInUse },
+	},
+	{
+		Name: "total_alloc", Unit: "bytes", goName: "TotalAlloc", IsCounter: true,
+		Description: "The memory allocated since the process started, in span resolution.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.TotalAlloc },
+	},
+	{
+		Name: "total_free", Unit: "bytes", goName: "TotalFree", IsCounter: true,
+		Description: "The memory freed since the process started, in span resolution.",
+		value:       func(s MemLimitRelatedStats) uint64 { return s.TotalFree },
+	},
+}
+
+// FieldMetadata returns the name, unit, description and metric type of every MemLimitRelatedStats field,
+// in declaration order. Exporters can generate their metrics from it instead of listing the fields,
+// e.g. a counter for total_alloc and total_free, and gauges for the rest:
+//
+//	stats := rtml.GetMemLimitRelatedStats()
+//	for _, field := range rtml.FieldMetadata() {
+//		export(field.Name, field.Unit, field.IsCounter, field.Value(stats))
+//	}
+func FieldMetadata() []StatField {
+	return append([]StatField(nil), statFields...)
+}
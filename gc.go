@@ -0,0 +1,54 @@
+package rtml
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// minimum time between two garbage collections forced by ForceGCIfNearLimit.
+const forcedGCMinInterval = time.Second
+
+// heap live is considered "near" the heap goal above this fraction of it.
+const nearHeapGoalRatio = 0.9
+
+// unix nanoseconds of the last forced garbage collection.
+var lastForcedGC atomic.Int64
+
+// ForceGCIfNearLimit runs a blocking garbage collection (runtime.GC) when MemUtilizationRatio
+// is above threshold and the live heap is close to (or above) the heap goal,
+// and reports whether a collection was triggered.
+//
+// Sometimes reclaiming memory right away is cheaper than rejecting work that arrives just before
+// the runtime would have collected anyway. But forcing a GC is expensive: it stops the caller
+// until a full cycle completes and burns CPU on all cores, so use it sparingly.
+// To avoid thrashing the CPU when called in a loop, at most one forced collection
+// runs per second, and calls in between return false.
+func ForceGCIfNearLimit(threshold float64) bool {
+	if MemUtilizationRatio() <= threshold {
+		return false
+	}
+
+	heapGoal, ok := readHeapGoal()
+	if !ok {
+		return false
+	}
+	heapLive := runtimeGCController.heapLive.Load()
+	if float64(heapLive) < float64(heapGoal)*nearHeapGoalRatio {
+		// the runtime has enough room to allocate before the next cycle, a forced GC will not help much.
+		return false
+	}
+
+	now := time.Now().UnixNano()
+	last := lastForcedGC.Load()
+	if now-last < int64(forcedGCMinInterval) {
+		return false
+	}
+	if !lastForcedGC.CompareAndSwap(last, now) {
+		// another goroutine is forcing a collection right now.
+		return false
+	}
+
+	runtime.GC()
+	return true
+}
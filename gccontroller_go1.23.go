@@ -0,0 +1,98 @@
+//go:build go1.23 && !go1.28
+
+package rtml
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// miror the types from go runtime which uses sysMemStat.
+// https://github.com/golang/go/blob/44c5956bf7454ca178c596eb87578ea61d6c9dee/src/runtime/mstats.go#L643
+type sysMemStat uint64
+
+func (s *sysMemStat) load() uint64 {
+	return atomic.LoadUint64((*uint64)(s))
+}
+
+// following struct is a mirror of the exact struct used by the go runtime.
+// notice that it must match exactly (field order and types).
+// if go ever changes the internal struct, this need to be updated as well,
+// or we can get invalid values when accessing those fields.
+//
+// the layout was verified to be identical for go1.23 up to go1.27:
+// https://github.com/golang/go/blob/go1.27.1/src/runtime/mgcpacer.go
+//
+// when a new go version changes the runtime struct, copy this file with build tags
+// for the new version range, update the copy, and narrow the range of this file.
+// versions that are not covered by any mirror fail to build (see gccontroller_unsupported.go).
+type gcControllerState struct {
+	gcPercent                  atomic.Int32
+	memoryLimit                atomic.Int64
+	heapMinimum                uint64
+	runway                     atomic.Uint64
+	consMark                   float64
+	lastConsMark               [4]float64
+	gcPercentHeapGoal          atomic.Uint64
+	sweepDistMinTrigger        atomic.Uint64
+	triggered                  uint64
+	lastHeapGoal               uint64
+	heapLive                   atomic.Uint64
+	heapScan                   atomic.Uint64
+	lastHeapScan               uint64
+	lastStackScan              atomic.Uint64
+	maxStackScan               atomic.Uint64
+	globalsScan                atomic.Uint64
+	heapMarked                 uint64
+	heapScanWork               atomic.Int64
+	stackScanWork              atomic.Int64
+	globalsScanWork            atomic.Int64
+	bgScanCredit               atomic.Int64
+	assistTime                 atomic.Int64
+	dedicatedMarkTime          atomic.Int64
+	fractionalMarkTime         atomic.Int64
+	idleMarkTime               atomic.Int64
+	markStartTime              int64
+	dedicatedMarkWorkersNeeded atomic.Int64
+	idleMarkWorkers            atomic.Uint64
+	assistWorkPerByte          atomic.Uint64 // This was Float64 originally (from go internals). not used so don't matter
+	assistBytesPerWork         atomic.Uint64 // This was Float64 originally (from go internals). not used so don't matter
+	fractionalUtilizationGoal  float64
+
+	// fields used for memory limiting goal calculation
+	heapInUse    sysMemStat
+	heapReleased sysMemStat
+	heapFree     sysMemStat
+	totalAlloc   atomic.Uint64
+	totalFree    atomic.Uint64
+	mappedReady  atomic.Uint64
+
+	test bool
+	_    [64]byte
+}
+
+// go versions (major.minor) this mirror was verified against.
+var supportedGoVersions = []string{"go1.23", "go1.24", "go1.25", "go1.26", "go1.27"}
+
+// known good layout of the runtime struct for this go version range.
+// offsets are identical on 64-bit and 32-bit architectures (verified on 386, arm and mips):
+// every field is 8 bytes wide except gcPercent which is followed by an atomic.Int64,
+// and the 64-bit atomics are 8 bytes aligned on all architectures (both in sync/atomic and in the runtime).
+// the trailing padding differs per architecture, but it is never read.
+const (
+	expectedGCControllerSize  = 392
+	expectedMemoryLimitOffset = 8
+	expectedHeapLiveOffset    = 104
+	expectedHeapFreeOffset    = 288
+	expectedMappedReadyOffset = 312
+)
+
+// compile time assertions that the mirror matches the known good layout.
+// a mismatch fails the build with an "index out of bounds" (or "overflows") error.
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(gcControllerState{})-expectedGCControllerSize]
+	_ = [1]struct{}{}[unsafe.Offsetof(gcControllerState{}.memoryLimit)-expectedMemoryLimitOffset]
+	_ = [1]struct{}{}[unsafe.Offsetof(gcControllerState{}.heapLive)-expectedHeapLiveOffset]
+	_ = [1]struct{}{}[unsafe.Offsetof(gcControllerState{}.heapFree)-expectedHeapFreeOffset]
+	_ = [1]struct{}{}[unsafe.Offsetof(gcControllerState{}.mappedReady)-expectedMappedReadyOffset]
+)
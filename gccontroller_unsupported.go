@@ -0,0 +1,10 @@
+//go:build !go1.23 || go1.28
+
+package rtml
+
+// the gcControllerState mirror was not verified against the runtime of this go version.
+// instead of silently reading wrong fields at runtime, fail the build here.
+//
+// to add support for a new go version, compare the runtime gcControllerState struct
+// (src/runtime/mgcpacer.go) with the latest mirror, and add or extend a gccontroller_go*.go file.
+var _ = rtml_gcControllerState_mirror_is_not_verified_for_this_go_version
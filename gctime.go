@@ -0,0 +1,45 @@
+package rtml
+
+import "time"
+
+// CPU time the garbage collector spent marking, read from the runtime pacer.
+//
+// All the values are CPU time in nanoseconds (as time.Duration), summed across all the Ps,
+// so they can be larger than the wall clock time of the cycle.
+// The runtime resets them when a cycle starts, so they cover the cycle in progress,
+// or the last completed cycle when no collection is running.
+type GCTimeStats struct {
+	// time goroutines spent in mark assists: allocating goroutines are drafted into marking
+	// to pay for their allocations, which shows up as latency in the application.
+	AssistTime time.Duration
+
+	// time spent by dedicated mark workers, which take a whole P for the duration of the cycle.
+	DedicatedMarkTime time.Duration
+
+	// time spent by fractional mark workers, which run part time to reach the 25% CPU target.
+	FractionalMarkTime time.Duration
+
+	// time spent by mark workers on otherwise idle Ps.
+	IdleMarkTime time.Duration
+}
+
+// Total returns the sum of all the GC CPU times.
+func (s GCTimeStats) Total() time.Duration {
+	return s.AssistTime + s.DedicatedMarkTime + s.FractionalMarkTime + s.IdleMarkTime
+}
+
+// GetGCTimeStats returns how much CPU time the garbage collector is burning in the current cycle.
+//
+// When the memory limit is tight the collector runs back to back, and allocating goroutines
+// spend more and more of their time in assists. Correlating a high AssistTime with
+// IsMemLimitReached returning true explains latency spikes near the limit.
+//
+// Like GetMemLimitRelatedStats, the values are read one by one without a consistent view.
+func GetGCTimeStats() GCTimeStats {
+	return GCTimeStats{
+		AssistTime:         time.Duration(runtimeGCController.assistTime.Load()),
+		DedicatedMarkTime:  time.Duration(runtimeGCController.dedicatedMarkTime.Load()),
+		FractionalMarkTime: time.Duration(runtimeGCController.fractionalMarkTime.Load()),
+		IdleMarkTime:       time.Duration(runtimeGCController.idleMarkTime.Load()),
+	}
+}
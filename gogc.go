@@ -0,0 +1,142 @@
+package rtml
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// utilization ratios between which GOGCController moves GOGC from max to min.
+const (
+	gogcSlackUtilization    = 0.5
+	gogcPressureUtilization = 0.9
+)
+
+// GOGC values are adjusted in steps of this size, to avoid calling debug.SetGCPercent
+// (which briefly stops the world) on every tiny change in utilization.
+const gogcStep = 10
+
+// GOGCController adjusts GOGC (debug.SetGCPercent) according to the memory pressure.
+//
+// As utilization rises, GOGC is lowered towards the configured minimum, so the heap goal is
+// kept closer to the live heap and more memory headroom is left, at the cost of more frequent
+// collections (CPU). When there is slack, GOGC is raised back towards the maximum to save CPU.
+// Utilization at or below 50% of the limit maps to the maximum, at or above 90% to the minimum,
+// and values in between are interpolated.
+//
+// The original GOGC value is restored on Stop.
+type GOGCController struct {
+	source   StatsSource
+	interval time.Duration
+	minGOGC  int
+	maxGOGC  int
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	original int
+
+	// only written by the controller goroutine (and on Start).
+	current atomic.Int64
+}
+
+// NewGOGCController creates a controller that re-evaluates GOGC every interval (1 second when it is not positive),
+// keeping it within [minGOGC, maxGOGC].
+func NewGOGCController(interval time.Duration, minGOGC, maxGOGC int) *GOGCController {
+	return NewGOGCControllerFor(interval, minGOGC, maxGOGC, runtimeStatsSource{})
+}
+
+// NewGOGCControllerFor creates a controller that follows the utilization of source instead of the go runtime.
+// GOGC itself is still set on the runtime. See StatsSource.
+func NewGOGCControllerFor(interval time.Duration, minGOGC, maxGOGC int, source StatsSource) *GOGCController {
+	if minGOGC < 1 {
+		minGOGC = 1
+	}
+	if maxGOGC < minGOGC {
+		maxGOGC = minGOGC
+	}
+	return &GOGCController{
+		source:   sourceOrRuntime(source),
+		interval: pollInterval(interval),
+		minGOGC:  minGOGC,
+		maxGOGC:  maxGOGC,
+	}
+}
+
+// Start begins adjusting GOGC in a background goroutine.
+// Calling Start on a running controller is a no-op.
+func (c *GOGCController) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return
+	}
+
+	c.original = int(runtimeGCController.gcPercent.Load())
+	c.current.Store(int64(c.original))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(ctx, c.done)
+}
+
+// Stop terminates the controller and restores the GOGC value that was set when it started.
+func (c *GOGCController) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel == nil {
+		return
+	}
+
+	c.cancel()
+	<-c.done
+	c.cancel = nil
+	c.done = nil
+	debug.SetGCPercent(c.original)
+}
+
+// Current returns the GOGC value last set by the controller.
+func (c *GOGCController) Current() int {
+	return int(c.current.Load())
+}
+
+func (c *GOGCController) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.adjust()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *GOGCController) adjust() {
+	target := c.targetFor(utilizationRatioOf(c.source))
+	if int64(target) != c.current.Load() {
+		debug.SetGCPercent(target)
+		c.current.Store(int64(target))
+	}
+}
+
+func (c *GOGCController) targetFor(utilization float64) int {
+	switch {
+	case utilization <= gogcSlackUtilization:
+		return c.maxGOGC
+	case utilization >= gogcPressureUtilization:
+		return c.minGOGC
+	}
+
+	fraction := (utilization - gogcSlackUtilization) / (gogcPressureUtilization - gogcSlackUtilization)
+	target := c.maxGOGC - int(fraction*float64(c.maxGOGC-c.minGOGC))
+	target = target / gogcStep * gogcStep
+	return max(c.minGOGC, min(c.maxGOGC, target))
+}
@@ -0,0 +1,23 @@
+package rtml
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestNewGOGCControllerNonPositiveInterval(t *testing.T) {
+	original := debug.SetGCPercent(100)
+	defer debug.SetGCPercent(original)
+
+	c := NewGOGCControllerFor(0, 50, 200, &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 100}})
+	if c.interval != defaultPollInterval {
+		t.Errorf("interval = %v, want %v", c.interval, defaultPollInterval)
+	}
+
+	// must not panic, and restores GOGC when stopped.
+	c.Start()
+	c.Stop()
+	if gcPercent := debug.SetGCPercent(original); gcPercent != 100 {
+		t.Errorf("GOGC = %d after Stop, want 100", gcPercent)
+	}
+}
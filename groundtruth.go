@@ -0,0 +1,123 @@
+package rtml
+
+import "runtime/metrics"
+
+// FieldDiscrepancy is the difference between a stats field read through the runtime internals
+// and the same quantity reported by runtime/metrics.
+type FieldDiscrepancy struct {
+	// snake case name of the field, e.g. "heap_free", as in FieldMetadata.
+	Name string
+
+	// the value read by GetMemLimitRelatedStats.
+	Rtml uint64
+
+	// the value computed from runtime/metrics.
+	Metrics uint64
+
+	// |Rtml - Metrics| in bytes.
+	Diff uint64
+
+	// Diff as a fraction of the memory limit, the scale IsMemLimitReached decides at,
+	// so a few KiB on a small field (e.g. heap_free) don't read as a large error.
+	// Without a memory limit, it is a fraction of Metrics (1 when Metrics is 0).
+	Relative float64
+}
+
+// GroundTruthComparison compares the stats behind IsMemLimitReached with runtime/metrics, see CompareWithRuntimeMetrics.
+type GroundTruthComparison struct {
+	// one entry per field with a runtime/metrics counterpart, in FieldMetadata order.
+	Fields []FieldDiscrepancy
+
+	// the largest relative discrepancy of Fields, and the field it was observed on.
+	MaxRelative float64
+	MaxField    string
+
+	// the decision of IsMemLimitReached, and the same decision made from the runtime/metrics values.
+	Reached        bool
+	MetricsReached bool
+}
+
+// the runtime/metrics read by CompareWithRuntimeMetrics, by index.
+var groundTruthMetrics = []string{
+	"/gc/gomemlimit:bytes",
+	"/gc/heap/goal:bytes",
+	"/memory/classes/heap/objects:bytes",
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+	"/memory/classes/heap/free:bytes",
+	"/memory/classes/heap/unused:bytes",
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/frees:bytes",
+}
+
+// CompareWithRuntimeMetrics reads the stats through the runtime internals (the cheap path IsMemLimitReached uses),
+// and the same quantities through runtime/metrics (the canonical, more expensive source), and reports the discrepancies.
+// It is meant for validation, e.g. sampled periodically in a soak test, to measure how accurate the cheap path is.
+//
+// Some differences are expected and are what it quantifies:
+//   - the two sources are read one after the other, while the process keeps allocating.
+//   - the runtime internals are updated in span resolution (e.g. HeapLive counts whole spans cached for allocation),
+//     while runtime/metrics counts objects, and flushes per-P caches first.
+//   - HeapLive is compared with the heap objects, MappedReady with the total mapped memory minus the released heap,
+//     and HeapInUse with the heap objects plus the unused parts of their spans.
+//
+// It doesn't stop the world, but allocates and is much slower than IsMemLimitReached: don't call it per request.
+func CompareWithRuntimeMetrics() GroundTruthComparison {
+	reached := IsMemLimitReached()
+	stats := GetMemLimitRelatedStats()
+
+	samples := make([]metrics.Sample, len(groundTruthMetrics))
+	for i, name := range groundTruthMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	values := make([]uint64, len(samples))
+	for i, sample := range samples {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			values[i] = sample.Value.Uint64()
+		}
+	}
+
+	truth := MemLimitRelatedStats{
+		MemoryLimit:  values[0],
+		HeapGoal:     values[1],
+		HeapLive:     values[2],
+		MappedReady:  values[3] - min(values[4], values[3]),
+		HeapReleased: values[4],
+		HeapFree:     values[5],
+		HeapInUse:    values[2] + values[6],
+		TotalAlloc:   values[7],
+		TotalFree:    values[8],
+	}
+
+	comparison := GroundTruthComparison{
+		Fields:         make([]FieldDiscrepancy, 0, len(statFields)),
+		Reached:        reached,
+		MetricsReached: MemLimitReachedFor(truth),
+	}
+	for _, f := range statFields {
+		d := FieldDiscrepancy{Name: f.Name, Rtml: f.value(stats), Metrics: f.value(truth)}
+		d.Diff = max(d.Rtml, d.Metrics) - min(d.Rtml, d.Metrics)
+		d.Relative = relativeDiscrepancy(d.Diff, d.Metrics, truth.MemoryLimit)
+		if d.Relative > comparison.MaxRelative {
+			comparison.MaxRelative = d.Relative
+			comparison.MaxField = d.Name
+		}
+		comparison.Fields = append(comparison.Fields, d)
+	}
+	return comparison
+}
+
+func relativeDiscrepancy(diff, expected, memoryLimit uint64) float64 {
+	if diff == 0 {
+		return 0
+	}
+	scale := memoryLimit
+	if memoryLimit == 0 || memoryLimit == noMemoryLimit {
+		scale = expected
+	}
+	if scale == 0 {
+		return 1
+	}
+	return float64(diff) / float64(scale)
+}
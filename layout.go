@@ -0,0 +1,106 @@
+package rtml
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+var (
+	layoutOnce sync.Once
+	layoutErr  error
+)
+
+// LayoutVerified checks that the mirror of the runtime internal struct can be trusted
+// on the running process, and returns a descriptive error if it can't.
+//
+// It performs the following checks:
+//   - the size and offsets of key fields (memoryLimit, heapLive, heapFree, mappedReady)
+//     match the known good values for the go version this package was compiled with.
+//   - the runtime struct is 8 bytes aligned, as required for 64-bit atomics on 32-bit architectures.
+//   - values read through the mirror agree with the values the runtime publishes
+//     via runtime/metrics (memory limit and GOGC), which can only happen if the
+//     linkname resolved to the struct we expect.
+//
+// The check runs once, and the result is cached for later calls.
+func LayoutVerified() error {
+	layoutOnce.Do(func() {
+		layoutErr = verifyLayout()
+	})
+	return layoutErr
+}
+
+func verifyLayout() error {
+	var c gcControllerState
+	checks := []struct {
+		name             string
+		actual, expected uintptr
+	}{
+		{"sizeof(gcControllerState)", unsafe.Sizeof(c), expectedGCControllerSize},
+		{"offsetof(memoryLimit)", unsafe.Offsetof(c.memoryLimit), expectedMemoryLimitOffset},
+		{"offsetof(heapLive)", unsafe.Offsetof(c.heapLive), expectedHeapLiveOffset},
+		{"offsetof(heapFree)", unsafe.Offsetof(c.heapFree), expectedHeapFreeOffset},
+		{"offsetof(mappedReady)", unsafe.Offsetof(c.mappedReady), expectedMappedReadyOffset},
+	}
+
+	var mismatches []string
+	for _, check := range checks {
+		if check.actual != check.expected {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %d, actual %d", check.name, check.expected, check.actual))
+		}
+	}
+
+	// 64-bit atomic loads panic on 32-bit architectures if the value is not 8 bytes aligned.
+	if addr := uintptr(unsafe.Pointer(&runtimeGCController)); addr%8 != 0 {
+		mismatches = append(mismatches, fmt.Sprintf("gcController address %#x is not 8 bytes aligned", addr))
+	}
+
+	samples := []metrics.Sample{
+		{Name: "/gc/gomemlimit:bytes"},
+		{Name: "/gc/gogc:percent"},
+	}
+	metrics.Read(samples)
+
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		expected := samples[0].Value.Uint64()
+		actual := uint64(runtimeGCController.memoryLimit.Load())
+		if expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("memoryLimit: runtime/metrics reports %d, mirror reads %d", expected, actual))
+		}
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		expected := samples[1].Value.Uint64()
+		// GOGC=off is stored as -1, and reported by runtime/metrics as the same bits (max uint64).
+		actual := uint64(int64(runtimeGCController.gcPercent.Load()))
+		if expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("gcPercent: runtime/metrics reports %d, mirror reads %d", expected, actual))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("rtml: gcControllerState layout mismatch: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// IsSupported reports whether the values read from the runtime can be trusted on this process.
+//
+// It returns false if LayoutVerified fails, or if reading the runtime internals ever panicked
+// (see IsMemLimitReached), as a bool for easy branching.
+// Applications can use it to degrade gracefully, e.g. skip memory aware admission altogether,
+// instead of acting on bogus numbers:
+//
+//	if rtml.IsSupported() && rtml.IsMemLimitReached() {
+//		return errResourceExhausted
+//	}
+func IsSupported() bool {
+	return !degraded.Load() && LayoutVerified() == nil
+}
+
+// SupportedGoVersions returns the go versions (e.g. "go1.23") the runtime struct mirror was verified against.
+// Building with any other go version fails at compile time.
+func SupportedGoVersions() []string {
+	return append([]string(nil), supportedGoVersions...)
+}
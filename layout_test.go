@@ -0,0 +1,29 @@
+package rtml
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestLayoutVerified(t *testing.T) {
+	if err := LayoutVerified(); err != nil {
+		t.Fatalf("LayoutVerified() = %v", err)
+	}
+
+	var c gcControllerState
+	checks := []struct {
+		name             string
+		actual, expected uintptr
+	}{
+		{"sizeof(gcControllerState)", unsafe.Sizeof(c), expectedGCControllerSize},
+		{"offsetof(memoryLimit)", unsafe.Offsetof(c.memoryLimit), expectedMemoryLimitOffset},
+		{"offsetof(heapLive)", unsafe.Offsetof(c.heapLive), expectedHeapLiveOffset},
+		{"offsetof(heapFree)", unsafe.Offsetof(c.heapFree), expectedHeapFreeOffset},
+		{"offsetof(mappedReady)", unsafe.Offsetof(c.mappedReady), expectedMappedReadyOffset},
+	}
+	for _, check := range checks {
+		if check.actual != check.expected {
+			t.Errorf("%s = %d, want %d", check.name, check.actual, check.expected)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package rtml
+
+import (
+	"context"
+	"sync"
+)
+
+// MemGroup is like errgroup.Group, for a fan-out of memory heavy tasks that self-throttles:
+// Go only starts a task while the memory limit is not reached, and blocks the caller otherwise.
+//
+// Tasks that are already running are not interrupted. As in errgroup, the first task to return
+// an error cancels the context of the group (see NewMemGroup), and is returned by Wait.
+// The zero value is ready to use, without a shared context.
+type MemGroup struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	source StatsSource // nil reads the runtime
+
+	wg sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewMemGroup returns a group and a context derived from ctx, which is cancelled
+// the first time a task returns an error, or when Wait returns, whichever occurs first.
+func NewMemGroup(ctx context.Context) (*MemGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &MemGroup{ctx: ctx, cancel: cancel}, ctx
+}
+
+// NewMemGroupFor is like NewMemGroup, for a group that waits while source reports the limit reached,
+// instead of the go runtime. See StatsSource.
+func NewMemGroupFor(ctx context.Context, source StatsSource) (*MemGroup, context.Context) {
+	g, ctx := NewMemGroup(ctx)
+	g.source = source
+	return g, ctx
+}
+
+// Go waits until IsMemLimitReached returns false, then calls fn in a new goroutine.
+//
+// While waiting, IsMemLimitReached is polled with the same bounded backoff as ThrottledReader.
+// If the group context is done before the task could start (a task already failed, or the parent was cancelled),
+// fn is never called, and the context error is recorded as the group error if there is none yet.
+func (g *MemGroup) Go(fn func() error) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// checked first, waitWhile only looks at the context while the limit is reached.
+	if ctx.Err() != nil || waitWhile(ctx, sourceOrRuntime(g.source).Reached) != nil {
+		g.setError(context.Cause(ctx))
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.setError(err)
+		}
+	}()
+}
+
+// Wait blocks until all the started tasks have returned, then returns the first error (if any).
+func (g *MemGroup) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
+
+func (g *MemGroup) setError(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel(err)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package rtml
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemGroupGoAfterCancel(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g, _ := NewMemGroup(parent)
+	called := false
+	g.Go(func() error {
+		called = true
+		return nil
+	})
+	if err := g.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+	if called {
+		t.Errorf("fn was called with a cancelled context")
+	}
+}
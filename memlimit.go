@@ -0,0 +1,118 @@
+package rtml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+)
+
+// CgroupLimitOption customizes AutoSetMemoryLimitFromCgroup.
+type CgroupLimitOption func(*cgroupLimitConfig)
+
+type cgroupLimitConfig struct {
+	preferHigh bool
+}
+
+// WithCgroupMemoryHigh derives the memory limit from the cgroup memory.high soft limit when it is set,
+// falling back to memory.max when it isn't (or on cgroup v1).
+//
+// Going over memory.max gets the process OOM killed, while going over memory.high gets it throttled
+// into reclaim stalls. Targeting memory.high keeps the garbage collector working before the kernel starts
+// throttling, at the cost of using less of the memory the container is allowed. See CgroupMemoryHigh.
+func WithCgroupMemoryHigh() CgroupLimitOption {
+	return func(c *cgroupLimitConfig) {
+		c.preferHigh = true
+	}
+}
+
+// AutoSetMemoryLimitFromCgroup sets the runtime memory limit (same as GOMEMLIMIT) from the
+// cgroup (container) memory limit, keeping reserveFraction of it as headroom.
+//
+// The reserve accounts for memory that is not managed by the go heap (cgo, mmap, page cache, etc.)
+// and for the inaccuracies of the soft limit. For example, with a 512MiB container limit
+// and reserveFraction of 0.1, the memory limit is set to ~460MiB.
+//
+// By default the limit is derived from the hard limit (memory.max), pass WithCgroupMemoryHigh
+// to derive it from the soft limit (memory.high) when it is set.
+//
+// It returns the limit that was set. An error is returned, and the limit is not changed,
+// if reserveFraction is not in [0, 1) or if no cgroup memory limit can be found.
+//
+// Call it once on startup, before any work is accepted.
+func AutoSetMemoryLimitFromCgroup(reserveFraction float64, opts ...CgroupLimitOption) (int64, error) {
+	if math.IsNaN(reserveFraction) || reserveFraction < 0 || reserveFraction >= 1 {
+		return 0, fmt.Errorf("rtml: reserve fraction must be in [0, 1), got %v", reserveFraction)
+	}
+
+	var config cgroupLimitConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	cgroupLimit, err := cgroupTargetLimit(config)
+	if err != nil {
+		return 0, err
+	}
+
+	return setMemoryLimitBelow(cgroupLimit, uint64(float64(cgroupLimit)*(1-reserveFraction)))
+}
+
+// the cgroup limit to derive the memory limit from: memory.high when preferred and set
+// (capped to memory.max), memory.max otherwise.
+func cgroupTargetLimit(config cgroupLimitConfig) (uint64, error) {
+	if config.preferHigh {
+		high, err := CgroupMemoryHigh()
+		if err == nil {
+			// memory.high can be configured above memory.max, which still kills first.
+			if cgroupMax, err := CgroupMemoryMax(); err == nil && cgroupMax < high {
+				return cgroupMax, nil
+			}
+			return high, nil
+		}
+		if !errors.Is(err, ErrNoCgroupMemoryLimit) && !errors.Is(err, ErrCgroupV1Unsupported) {
+			return 0, err
+		}
+	}
+	return CgroupMemoryMax()
+}
+
+// SetMemoryLimitPercent sets the runtime memory limit (same as GOMEMLIMIT) to percent% of the
+// cgroup (container) hard memory limit (memory.max), e.g. 90 for a 512MiB container sets ~460MiB.
+//
+// It returns the limit that was set. An error is returned, and the limit is not changed,
+// if percent is not in (0, 100] or if no cgroup memory limit can be found.
+// The limit never exceeds the cgroup limit.
+//
+// Call it once on startup, before any work is accepted.
+func SetMemoryLimitPercent(percent int) (int64, error) {
+	if percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("rtml: memory limit percent must be in (0, 100], got %d", percent)
+	}
+
+	cgroupLimit, err := CgroupMemoryMax()
+	if err != nil {
+		return 0, err
+	}
+
+	// split to avoid overflowing cgroupLimit*percent for huge limits, while staying exact
+	p := uint64(percent)
+	limit := cgroupLimit/100*p + cgroupLimit%100*p/100
+	return setMemoryLimitBelow(cgroupLimit, limit)
+}
+
+// sets the runtime memory limit to limit, refusing values above ceiling.
+func setMemoryLimitBelow(ceiling uint64, limit uint64) (int64, error) {
+	if limit > ceiling {
+		return 0, fmt.Errorf("rtml: memory limit %d is above the cgroup limit %d", limit, ceiling)
+	}
+	if limit == 0 {
+		return 0, fmt.Errorf("rtml: computed memory limit is zero (cgroup limit %d)", ceiling)
+	}
+	if limit > math.MaxInt64 {
+		limit = math.MaxInt64
+	}
+
+	debug.SetMemoryLimit(int64(limit))
+	return int64(limit), nil
+}
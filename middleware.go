@@ -0,0 +1,223 @@
+package rtml
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MiddlewareOption customizes the requests rejected by Middleware and the framework adapters.
+type MiddlewareOption func(*RequestFilter)
+
+// WithExcludedPaths exempts requests to paths from rejection, e.g. health checks and metrics scraping,
+// which should keep working when the memory limit is reached.
+// A path ending with "*" matches any path with that prefix.
+func WithExcludedPaths(paths ...string) MiddlewareOption {
+	return func(f *RequestFilter) {
+		for _, path := range paths {
+			if prefix, ok := strings.CutSuffix(path, "*"); ok {
+				f.excludedPrefixes = append(f.excludedPrefixes, prefix)
+			} else {
+				f.excludedPaths = append(f.excludedPaths, path)
+			}
+		}
+	}
+}
+
+// WithRejectLevel sets the pressure level from which requests are rejected.
+// The default is PressureLevelCritical, which rejects exactly when IsMemLimitReached returns true.
+// PressureLevelWarning starts rejecting earlier, when the memory in use crosses the low watermark
+// (see WithLowWatermark).
+func WithRejectLevel(level MemoryPressureLevel) MiddlewareOption {
+	return func(f *RequestFilter) {
+		f.level = level
+	}
+}
+
+// bounds of the retry hint computed by WithRetryAfter.
+const (
+	minRetryAfter = time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// WithRetryAfter adds a Retry-After hint to rejected requests, telling clients when memory is likely available again.
+// It is computed from the recent shrink rate of the heap in ring (see SampleRing.TimeUntilRecovery),
+// rounded up to whole seconds and bounded between 1 second and 5 minutes.
+// fallback is used when ring is nil or has no trend to extrapolate from yet.
+//
+// The ring must be sampled periodically by the application, e.g. every second:
+//
+//	ring := rtml.NewSampleRing(10)
+//	go func() {
+//		for range time.Tick(time.Second) {
+//			ring.Sample()
+//		}
+//	}()
+//	handler := rtml.Middleware(mux, rtml.WithRetryAfter(ring, 5*time.Second))
+func WithRetryAfter(ring *SampleRing, fallback time.Duration) MiddlewareOption {
+	return func(f *RequestFilter) {
+		f.retryRing = ring
+		f.retryFallback = fallback
+		f.retryAfter = true
+	}
+}
+
+// WithRouteLimitRatio rejects requests to path once the memory in use reaches ratio of the memory limit
+// (see IsMemLimitReachedAt), instead of the reject level of the filter. It is the building block of tiered admission:
+// expensive routes can be shed early while cheap ones keep being served until the limit:
+//
+//	handler := rtml.Middleware(mux,
+//		rtml.WithRouteLimitRatio("/api/export*", 0.8), // expensive, reject from 80% of the limit
+//		rtml.WithRouteLimitRatio("/api/*", 0.95),      // cheap, reject from 95%
+//	)
+//
+// A path ending with "*" matches any path with that prefix. An exact path wins over prefixes,
+// and the longest matching prefix wins over shorter ones. Excluded paths (WithExcludedPaths) are never rejected.
+func WithRouteLimitRatio(path string, ratio float64) MiddlewareOption {
+	return func(f *RequestFilter) {
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			f.routeRatioPrefixes = append(f.routeRatioPrefixes, routeRatio{path: prefix, ratio: ratio})
+		} else {
+			f.routeRatios = append(f.routeRatios, routeRatio{path: path, ratio: ratio})
+		}
+	}
+}
+
+// WithStatsSource reads the memory limit state from source instead of the go runtime,
+// e.g. to test the rejection behavior with scripted values. See StatsSource.
+func WithStatsSource(source StatsSource) MiddlewareOption {
+	return func(f *RequestFilter) {
+		f.source = sourceOrRuntime(source)
+	}
+}
+
+// RequestFilter decides which requests to reject based on the memory limit state.
+// It holds the options shared by Middleware and the framework adapters
+// (in the middleware/rtmlgin and middleware/rtmlecho modules), so they behave the same.
+type RequestFilter struct {
+	source           StatsSource
+	level            MemoryPressureLevel
+	excludedPaths    []string
+	excludedPrefixes []string
+
+	retryAfter    bool
+	retryRing     *SampleRing
+	retryFallback time.Duration
+
+	routeRatios        []routeRatio
+	routeRatioPrefixes []routeRatio
+}
+
+// the limit ratio of a route, see WithRouteLimitRatio.
+type routeRatio struct {
+	path  string
+	ratio float64
+}
+
+// NewRequestFilter creates a filter from opts.
+func NewRequestFilter(opts ...MiddlewareOption) *RequestFilter {
+	f := &RequestFilter{source: runtimeStatsSource{}, level: PressureLevelCritical}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// ShouldReject reports whether a request to path should be rejected.
+func (f *RequestFilter) ShouldReject(path string) bool {
+	if f.isExcluded(path) {
+		return false
+	}
+	if ratio, ok := f.routeLimitRatio(path); ok {
+		return memLimitReachedAtOf(f.source, ratio)
+	}
+	if f.level == PressureLevelCritical {
+		// the common case, skip computing the utilization ratio.
+		return f.source.Reached()
+	}
+	return pressureLevelOf(f.source) >= f.level
+}
+
+// RetryAfter returns how long a rejected client should wait before retrying,
+// and false when WithRetryAfter was not passed. Adapters for protocols other than http
+// (e.g. a gRPC retry pushback) can use it to set their own hint.
+func (f *RequestFilter) RetryAfter() (time.Duration, bool) {
+	if !f.retryAfter {
+		return 0, false
+	}
+
+	retryAfter := f.retryFallback
+	if f.retryRing != nil {
+		if recovery, ok := f.retryRing.TimeUntilRecovery(); ok {
+			retryAfter = recovery
+		}
+	}
+	return min(max(retryAfter, minRetryAfter), maxRetryAfter), true
+}
+
+// RetryAfterHeader returns the value of the Retry-After http header for a rejected request (in seconds),
+// and false when WithRetryAfter was not passed.
+func (f *RequestFilter) RetryAfterHeader() (string, bool) {
+	retryAfter, ok := f.RetryAfter()
+	if !ok {
+		return "", false
+	}
+	seconds := (retryAfter + time.Second - 1) / time.Second
+	return strconv.FormatInt(int64(seconds), 10), true
+}
+
+// the ratio of the most specific route matching path, and false when none matches.
+func (f *RequestFilter) routeLimitRatio(path string) (float64, bool) {
+	for _, route := range f.routeRatios {
+		if path == route.path {
+			return route.ratio, true
+		}
+	}
+	best := -1
+	for i, route := range f.routeRatioPrefixes {
+		if strings.HasPrefix(path, route.path) && (best < 0 || len(route.path) > len(f.routeRatioPrefixes[best].path)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return f.routeRatioPrefixes[best].ratio, true
+}
+
+func (f *RequestFilter) isExcluded(path string) bool {
+	for _, excluded := range f.excludedPaths {
+		if path == excluded {
+			return true
+		}
+	}
+	for _, prefix := range f.excludedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryLimitReachedMessage is the response body of rejected requests.
+const MemoryLimitReachedMessage = "memory limit reached"
+
+// Middleware wraps next, responding with 503 Service Unavailable instead of
+// calling it when the memory limit is reached, so the client can retry later
+// (ideally on a different replica):
+//
+//	http.ListenAndServe(":8080", rtml.Middleware(mux, rtml.WithExcludedPaths("/healthz", "/metrics")))
+func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	filter := NewRequestFilter(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if filter.ShouldReject(r.URL.Path) {
+			if retryAfter, ok := filter.RetryAfterHeader(); ok {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, MemoryLimitReachedMessage, http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,29 @@
+// Package rtmlecho adapts the go-rtml request rejection middleware to echo.
+// It lives in its own module, so applications that don't use echo don't depend on it.
+package rtmlecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// EchoMemoryLimit returns an echo middleware that responds with 503 Service Unavailable
+// when the memory limit is reached. It accepts the same options as rtml.Middleware:
+//
+//	e.Use(rtmlecho.EchoMemoryLimit(rtml.WithExcludedPaths("/healthz")))
+func EchoMemoryLimit(opts ...rtml.MiddlewareOption) echo.MiddlewareFunc {
+	filter := rtml.NewRequestFilter(opts...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if filter.ShouldReject(c.Request().URL.Path) {
+				if retryAfter, ok := filter.RetryAfterHeader(); ok {
+					c.Response().Header().Set("Retry-After", retryAfter)
+				}
+				return c.String(http.StatusServiceUnavailable, rtml.MemoryLimitReachedMessage)
+			}
+			return next(c)
+		}
+	}
+}
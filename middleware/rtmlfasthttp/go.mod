@@ -0,0 +1,16 @@
+module github.com/odigos-io/go-rtml/middleware/rtmlfasthttp
+
+go 1.23.0
+
+require (
+	github.com/odigos-io/go-rtml v0.0.0
+	github.com/valyala/fasthttp v1.51.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.5 // indirect
+	github.com/klauspost/compress v1.17.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+)
+
+replace github.com/odigos-io/go-rtml => ../..
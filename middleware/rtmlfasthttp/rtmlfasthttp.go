@@ -0,0 +1,27 @@
+// Package rtmlfasthttp adapts the go-rtml request rejection middleware to fasthttp.
+// It lives in its own module, so applications that don't use fasthttp don't depend on it.
+package rtmlfasthttp
+
+import (
+	rtml "github.com/odigos-io/go-rtml"
+	"github.com/valyala/fasthttp"
+)
+
+// MemoryLimitHandler wraps next, responding with 503 Service Unavailable instead of
+// calling it when the memory limit is reached. It accepts the same options as rtml.Middleware:
+//
+//	fasthttp.ListenAndServe(":8080", rtmlfasthttp.MemoryLimitHandler(handler, rtml.WithExcludedPaths("/healthz")))
+func MemoryLimitHandler(next fasthttp.RequestHandler, opts ...rtml.MiddlewareOption) fasthttp.RequestHandler {
+	filter := rtml.NewRequestFilter(opts...)
+	return func(ctx *fasthttp.RequestCtx) {
+		if filter.ShouldReject(string(ctx.Path())) {
+			// Error resets the response headers, so the hint is set after it.
+			ctx.Error(rtml.MemoryLimitReachedMessage, fasthttp.StatusServiceUnavailable)
+			if retryAfter, ok := filter.RetryAfterHeader(); ok {
+				ctx.Response.Header.Set("Retry-After", retryAfter)
+			}
+			return
+		}
+		next(ctx)
+	}
+}
@@ -0,0 +1,33 @@
+package rtmlfasthttp
+
+import (
+	"testing"
+
+	rtml "github.com/odigos-io/go-rtml"
+	"github.com/valyala/fasthttp"
+)
+
+// BenchmarkMiddleware serves the same request with a bare handler, and with the handler wrapped by MemoryLimitHandler.
+// The difference between the two is the latency the middleware adds to every request.
+func BenchmarkMiddleware(b *testing.B) {
+	bare := fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	benchmarks := []struct {
+		name    string
+		handler fasthttp.RequestHandler
+	}{
+		{"bare", bare},
+		{"wrapped", MemoryLimitHandler(bare, rtml.WithExcludedPaths("/healthz"))},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/api/v1/items")
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bm.handler(&ctx)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Package rtmlgin adapts the go-rtml request rejection middleware to gin.
+// It lives in its own module, so applications that don't use gin don't depend on it.
+package rtmlgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// GinMemoryLimit returns a gin middleware that aborts requests with 503 Service Unavailable
+// when the memory limit is reached. It accepts the same options as rtml.Middleware:
+//
+//	router.Use(rtmlgin.GinMemoryLimit(rtml.WithExcludedPaths("/healthz")))
+func GinMemoryLimit(opts ...rtml.MiddlewareOption) gin.HandlerFunc {
+	filter := rtml.NewRequestFilter(opts...)
+	return func(c *gin.Context) {
+		if filter.ShouldReject(c.Request.URL.Path) {
+			if retryAfter, ok := filter.RetryAfterHeader(); ok {
+				c.Header("Retry-After", retryAfter)
+			}
+			c.String(http.StatusServiceUnavailable, rtml.MemoryLimitReachedMessage)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
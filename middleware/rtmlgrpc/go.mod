@@ -0,0 +1,12 @@
+module github.com/odigos-io/go-rtml/middleware/rtmlgrpc
+
+go 1.23.0
+
+require (
+	github.com/odigos-io/go-rtml v0.0.0
+	google.golang.org/grpc v1.65.0
+)
+
+require golang.org/x/sys v0.20.0 // indirect
+
+replace github.com/odigos-io/go-rtml => ../..
@@ -0,0 +1,57 @@
+// Package rtmlgrpc adapts go-rtml to grpc.
+// It lives in its own module, so applications that don't use grpc don't depend on it.
+package rtmlgrpc
+
+import (
+	"context"
+
+	rtml "github.com/odigos-io/go-rtml"
+	"google.golang.org/grpc/stats"
+)
+
+type limitRatioKey struct{}
+
+// statsHandler records rtml.LimitRatio at the start of every RPC into its context.
+type statsHandler struct{}
+
+// NewStatsHandler returns a grpc stats.Handler that records rtml.LimitRatio at the start of every RPC
+// into the RPC context, so logging and tracing downstream can correlate latency with the memory pressure
+// at that moment, e.g. to debug tail latency that coincides with GC. It costs a few atomic loads per RPC,
+// and never rejects anything:
+//
+//	server := grpc.NewServer(grpc.StatsHandler(rtmlgrpc.NewStatsHandler()))
+//
+//	func (s *service) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+//		if ratio, ok := rtmlgrpc.LimitRatioFromContext(ctx); ok {
+//			logger.InfoContext(ctx, "get", "limit_ratio", ratio)
+//		}
+//		...
+//	}
+//
+// It can be combined with other stats handlers (e.g. OpenTelemetry) by passing several grpc.StatsHandler options.
+func NewStatsHandler() stats.Handler {
+	return statsHandler{}
+}
+
+// LimitRatioFromContext returns the rtml.LimitRatio recorded when the RPC of ctx started,
+// and false when ctx doesn't belong to an RPC handled with NewStatsHandler.
+func LimitRatioFromContext(ctx context.Context) (float64, bool) {
+	ratio, ok := ctx.Value(limitRatioKey{}).(float64)
+	return ratio, ok
+}
+
+// TagRPC implements stats.Handler.
+func (statsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, limitRatioKey{}, rtml.LimitRatio())
+}
+
+// HandleRPC implements stats.Handler.
+func (statsHandler) HandleRPC(context.Context, stats.RPCStats) {}
+
+// TagConn implements stats.Handler.
+func (statsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (statsHandler) HandleConn(context.Context, stats.ConnStats) {}
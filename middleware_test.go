@@ -0,0 +1,108 @@
+package rtml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serves path through Middleware with opts, and returns the response status and the Retry-After header.
+func serveForTest(path string, opts ...MiddlewareOption) (int, string) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	Middleware(next, opts...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Header().Get("Retry-After")
+}
+
+func TestMiddleware(t *testing.T) {
+	reached := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1000, HeapGoal: 800, HeapLive: 900}}
+	reached.reached.Store(true)
+	notReached := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 500, HeapGoal: 800, HeapLive: 400}}
+
+	tests := []struct {
+		name           string
+		path           string
+		opts           []MiddlewareOption
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "admitted",
+			path:       "/api/items",
+			opts:       []MiddlewareOption{WithStatsSource(notReached)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rejected",
+			path:       "/api/items",
+			opts:       []MiddlewareOption{WithStatsSource(reached)},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "exempt path",
+			path:       "/healthz",
+			opts:       []MiddlewareOption{WithStatsSource(reached), WithExcludedPaths("/healthz", "/debug/*")},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "exempt prefix",
+			path:       "/debug/pprof/heap",
+			opts:       []MiddlewareOption{WithStatsSource(reached), WithExcludedPaths("/healthz", "/debug/*")},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "rejected with a retry hint",
+			path:           "/api/items",
+			opts:           []MiddlewareOption{WithStatsSource(reached), WithRetryAfter(nil, 3*time.Second)},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "3",
+		},
+		{
+			name:       "warning level",
+			path:       "/api/items",
+			opts:       []MiddlewareOption{WithStatsSource(&fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 990}}), WithRejectLevel(PressureLevelWarning)},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, retryAfter := serveForTest(tt.path, tt.opts...)
+			if status != tt.wantStatus || retryAfter != tt.wantRetryAfter {
+				t.Errorf("status = %d, Retry-After = %q, want %d, %q", status, retryAfter, tt.wantStatus, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRouteLimitRatio(t *testing.T) {
+	// 85% of the limit in use, not reached at the limit itself.
+	source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 900, HeapFree: 50, HeapGoal: 800, HeapLive: 600}}
+	opts := []MiddlewareOption{
+		WithStatsSource(source),
+		WithExcludedPaths("/api/export/health"),
+		WithRouteLimitRatio("/api/*", 0.95),
+		WithRouteLimitRatio("/api/export*", 0.8),
+		WithRouteLimitRatio("/api/export/small", 0.9),
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/api/export/big", wantStatus: http.StatusServiceUnavailable}, // longest prefix, 0.8
+		{path: "/api/export/small", wantStatus: http.StatusOK},               // exact path wins, 0.9
+		{path: "/api/items", wantStatus: http.StatusOK},                      // 0.95
+		{path: "/api/export/health", wantStatus: http.StatusOK},              // excluded
+		{path: "/other", wantStatus: http.StatusOK},                          // the reject level of the filter
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if status, _ := serveForTest(tt.path, opts...); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
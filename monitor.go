@@ -0,0 +1,348 @@
+package rtml
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// called by the Monitor whenever the pressure level changes.
+// stats is the snapshot the new level was computed from (see PressureLevelFor).
+type TransitionFunc func(from, to MemoryPressureLevel, stats MemLimitRelatedStats)
+
+// MonitorOption customizes a Monitor.
+type MonitorOption func(*Monitor)
+
+// WithHysteresis replaces the single warning threshold (the low watermark, see GetMemoryPressureLevel)
+// with separate rising and falling utilization ratios: the level rises to PressureLevelWarning once
+// the ratio reaches rise, and only falls back to PressureLevelNormal once the ratio drops below fall.
+// A ratio bouncing around a single threshold, as GC cycles naturally make it, then doesn't flood the callback.
+//
+// For example, WithHysteresis(0.9, 0.8) reports pressure from 90% of the limit, and clears it below 80%.
+// PressureLevelCritical still follows MemLimitReachedFor exactly, and falls back to PressureLevelWarning
+// (not PressureLevelNormal) while the ratio is still at or above fall.
+// fall is capped to rise.
+func WithHysteresis(rise, fall float64) MonitorOption {
+	return func(m *Monitor) {
+		m.hysteresis = true
+		m.rise = rise
+		m.fall = min(fall, rise)
+	}
+}
+
+// WithStaleDetection makes the monitor check that the values read from the runtime internals keep updating.
+// When HeapLive and TotalAlloc did not change for samples consecutive samples, while the process kept allocating
+// according to runtime/metrics, Stale starts returning true, until they update again.
+//
+// It is a cheap safety net for the rare pathological cases (a misbehaving linkname, a runtime bug)
+// where the rtml signal silently stops reflecting the process, e.g. to alert operators or to stop trusting it.
+func WithStaleDetection(samples int) MonitorOption {
+	return func(m *Monitor) {
+		m.staleAfter = max(samples, 1)
+	}
+}
+
+// WithMonitorStatsSource makes the monitor read the memory limit state from source instead of the go runtime,
+// e.g. to test the reactions to transitions with scripted values. See StatsSource.
+// Stale detection (WithStaleDetection) still compares the values with the runtime allocations.
+func WithMonitorStatsSource(source StatsSource) MonitorOption {
+	return func(m *Monitor) {
+		m.source = sourceOrRuntime(source)
+	}
+}
+
+// OnSustainedCritical calls cb once the pressure level stayed PressureLevelCritical for duration,
+// a sign the service can't recover by itself, e.g. to start a graceful shutdown that drains in flight requests
+// and lets the orchestrator replace the pod, instead of being OOM killed mid-request.
+//
+// Criticality must be sustained: every sample in between must be critical, and a single sample below
+// PressureLevelCritical restarts the count. cb is called once per critical episode, in its own goroutine,
+// so it can block or Stop the monitor. The precision is the sampling interval of the monitor.
+func OnSustainedCritical(duration time.Duration, cb func()) MonitorOption {
+	return func(m *Monitor) {
+		m.sustainedCritical = duration
+		m.onSustainedCritical = cb
+	}
+}
+
+// called by the Monitor when the cgroup memory limit changed and the memory limit was updated to follow it,
+// see WithCgroupLimitTracking. memoryLimit is the new runtime memory limit.
+type CgroupLimitChangeFunc func(oldCgroupLimit, newCgroupLimit uint64, memoryLimit int64)
+
+// WithCgroupLimitTracking makes the monitor re-read the cgroup memory limit (memory.max) every interval,
+// and when it changed, e.g. after a kubernetes in-place pod resize, set the runtime memory limit to the new cgroup limit
+// minus reserveFraction (like AutoSetMemoryLimitFromCgroup) and call onChange, which can be nil.
+// GOMEMLIMIT doesn't follow the container limit by itself, so without it the heuristic keeps comparing
+// against the limit of the old size after a vertical scale.
+//
+// The limit rarely changes, so the interval is independent of the sampling interval and should be coarse
+// (e.g. 30 seconds); the cgroup is read on the first sample after each interval. The first read only records
+// the limit, the memory limit is not touched until it changes. When the cgroup limit can't be read
+// (e.g. it was removed), the memory limit is left as it is. An invalid reserveFraction (not in [0, 1))
+// disables the tracking.
+func WithCgroupLimitTracking(interval time.Duration, reserveFraction float64, onChange CgroupLimitChangeFunc) MonitorOption {
+	return func(m *Monitor) {
+		if math.IsNaN(reserveFraction) || reserveFraction < 0 || reserveFraction >= 1 {
+			return
+		}
+		m.cgroupInterval = max(interval, time.Nanosecond)
+		m.cgroupReserve = reserveFraction
+		m.onCgroupChange = onChange
+	}
+}
+
+// Monitor polls the memory pressure level in a background loop,
+// and reports transitions between levels to a callback.
+//
+// It is useful for logging, metrics, or any other reaction that should happen once
+// when the pressure changes, rather than on every request.
+// Each sample reads a single stats snapshot, and computes the level from it like PressureLevelFor.
+// The first observation of every Run (or Start) is always reported as a transition from PressureLevelNormal,
+// and consecutive samples with the same level are never reported twice.
+type Monitor struct {
+	source       StatsSource
+	interval     time.Duration
+	onTransition TransitionFunc
+
+	hysteresis bool
+	rise, fall float64
+
+	// stale detection state, only accessed by the sampling loop (except stale).
+	staleAfter     int
+	unchanged      int
+	lastHeapLive   uint64
+	lastTotalAlloc uint64
+	lastAllocs     uint64
+	allocsSample   []metrics.Sample
+	stale          atomic.Bool
+
+	// sustained critical state, only accessed by the sampling loop.
+	sustainedCritical   time.Duration
+	onSustainedCritical func()
+	criticalSince       time.Time
+	sustainedFired      bool
+
+	// cgroup limit tracking state, only accessed by the sampling loop.
+	cgroupInterval  time.Duration
+	cgroupReserve   float64
+	onCgroupChange  CgroupLimitChangeFunc
+	lastCgroupCheck time.Time
+	cgroupLimit     uint64
+
+	level atomic.Int32
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// the interval of the polling helpers (Monitor, WatchMemLimitStats, StatsEmitter, GOGCController, StatsSSEHandler)
+// when the one passed is not positive.
+const defaultPollInterval = time.Second
+
+// interval, or defaultPollInterval when it is not positive (which time.NewTicker panics on).
+func pollInterval(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return defaultPollInterval
+	}
+	return interval
+}
+
+// NewMonitor creates a monitor that samples the pressure level every interval
+// (1 second when interval is not positive).
+// The monitor is not running until Start or Run is called.
+func NewMonitor(interval time.Duration, onTransition TransitionFunc, opts ...MonitorOption) *Monitor {
+	m := &Monitor{
+		source:       runtimeStatsSource{},
+		interval:     pollInterval(interval),
+		onTransition: onTransition,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Level returns the last pressure level observed by the monitor.
+func (m *Monitor) Level() MemoryPressureLevel {
+	return MemoryPressureLevel(m.level.Load())
+}
+
+// Stale reports whether the runtime values stopped updating while the process kept allocating.
+// It is always false unless the monitor was created with WithStaleDetection.
+func (m *Monitor) Stale() bool {
+	return m.stale.Load()
+}
+
+// Run samples the pressure level until ctx is cancelled.
+// It blocks, so it is usually invoked in its own goroutine.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		m.sample(first)
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) sample(first bool) {
+	stats := m.source.Stats()
+	prev := m.Level()
+	if first {
+		prev = PressureLevelNormal
+	}
+	level := m.pressureLevel(prev, stats)
+
+	m.level.Store(int32(level))
+	if (first || prev != level) && m.onTransition != nil {
+		m.onTransition(prev, level, stats)
+	}
+
+	if m.staleAfter > 0 {
+		m.detectStale(stats, first)
+	}
+	if m.onSustainedCritical != nil {
+		m.detectSustainedCritical(level, time.Now())
+	}
+	if m.cgroupInterval > 0 {
+		m.trackCgroupLimit(time.Now())
+	}
+}
+
+// re-reads the cgroup memory limit once per interval, and moves the memory limit with it when it changed.
+func (m *Monitor) trackCgroupLimit(now time.Time) {
+	if !m.lastCgroupCheck.IsZero() && now.Sub(m.lastCgroupCheck) < m.cgroupInterval {
+		return
+	}
+	m.lastCgroupCheck = now
+
+	cgroupLimit, err := CgroupMemoryMax()
+	if err != nil || cgroupLimit == m.cgroupLimit {
+		return
+	}
+	previous := m.cgroupLimit
+	m.cgroupLimit = cgroupLimit
+	if previous == 0 {
+		// the first read, nothing changed yet.
+		return
+	}
+
+	memoryLimit, err := setMemoryLimitBelow(cgroupLimit, uint64(float64(cgroupLimit)*(1-m.cgroupReserve)))
+	if err != nil {
+		return
+	}
+	if m.onCgroupChange != nil {
+		m.onCgroupChange(previous, cgroupLimit, memoryLimit)
+	}
+}
+
+// calls the OnSustainedCritical callback once the level stayed critical for the configured duration,
+// once per critical episode.
+func (m *Monitor) detectSustainedCritical(level MemoryPressureLevel, now time.Time) {
+	if level != PressureLevelCritical {
+		m.criticalSince = time.Time{}
+		m.sustainedFired = false
+		return
+	}
+	if m.criticalSince.IsZero() {
+		m.criticalSince = now
+	}
+	if !m.sustainedFired && now.Sub(m.criticalSince) >= m.sustainedCritical {
+		m.sustainedFired = true
+		go m.onSustainedCritical()
+	}
+}
+
+// counts the consecutive samples in which the process allocated (according to runtime/metrics),
+// while the values read from the runtime internals did not change.
+func (m *Monitor) detectStale(stats MemLimitRelatedStats, first bool) {
+	if m.allocsSample == nil {
+		m.allocsSample = []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	}
+	metrics.Read(m.allocsSample)
+	var allocs uint64
+	if m.allocsSample[0].Value.Kind() == metrics.KindUint64 {
+		allocs = m.allocsSample[0].Value.Uint64()
+	}
+
+	unchanged := stats.HeapLive == m.lastHeapLive && stats.TotalAlloc == m.lastTotalAlloc
+	working := allocs != m.lastAllocs
+	m.lastHeapLive, m.lastTotalAlloc, m.lastAllocs = stats.HeapLive, stats.TotalAlloc, allocs
+	if first {
+		return
+	}
+
+	switch {
+	case !unchanged:
+		m.unchanged = 0
+		m.stale.Store(false)
+	case working:
+		m.unchanged++
+		if m.unchanged >= m.staleAfter {
+			m.stale.Store(true)
+		}
+	}
+}
+
+// the pressure level of stats, with the hysteresis applied from the previous level when configured.
+func (m *Monitor) pressureLevel(prev MemoryPressureLevel, stats MemLimitRelatedStats) MemoryPressureLevel {
+	if !m.hysteresis {
+		return PressureLevelFor(stats)
+	}
+	if MemLimitReachedFor(stats) {
+		return PressureLevelCritical
+	}
+
+	threshold := m.rise
+	if prev >= PressureLevelWarning {
+		// already under pressure, only clear it once the ratio dropped below the falling threshold.
+		threshold = m.fall
+	}
+	if stats.LimitRatio() >= threshold {
+		return PressureLevelWarning
+	}
+	return PressureLevelNormal
+}
+
+// Start runs the monitor in a background goroutine until Stop is called.
+// Calling Start on a running monitor is a no-op.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		m.Run(ctx)
+	}(m.done)
+}
+
+// Stop terminates a monitor started with Start and waits for the loop to exit.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel == nil {
+		return
+	}
+
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+	m.done = nil
+}
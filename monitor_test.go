@@ -0,0 +1,231 @@
+package rtml
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestNewMonitorNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		m := NewMonitor(interval, nil, WithMonitorStatsSource(&fakeSource{}))
+		if m.interval != defaultPollInterval {
+			t.Errorf("NewMonitor(%v) interval = %v, want %v", interval, m.interval, defaultPollInterval)
+		}
+
+		// must not panic.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		m.Run(ctx)
+	}
+}
+
+// stats with the memory in use at ratio of the limit, reached when above the goal.
+func monitorStatsForTest(ratio float64, aboveGoal bool) MemLimitRelatedStats {
+	stats := MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: uint64(ratio * 1000), HeapGoal: 800, HeapLive: 500}
+	if aboveGoal {
+		stats.HeapLive = 900
+	}
+	return stats
+}
+
+// runs a single sample of m.
+func sampleOnceForTest(m *Monitor) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+}
+
+func TestMonitorTransitionReportsItsSnapshot(t *testing.T) {
+	source := &fakeSource{stats: monitorStatsForTest(1.1, true)}
+	type transition struct {
+		from, to MemoryPressureLevel
+		stats    MemLimitRelatedStats
+	}
+	var transitions []transition
+	m := NewMonitor(time.Second, func(from, to MemoryPressureLevel, stats MemLimitRelatedStats) {
+		transitions = append(transitions, transition{from, to, stats})
+	}, WithMonitorStatsSource(source))
+
+	sampleOnceForTest(m)
+	sampleOnceForTest(m)
+
+	// every run reports its first observation from PressureLevelNormal, even though the level is kept.
+	if len(transitions) != 2 {
+		t.Fatalf("got %d transitions, want 2", len(transitions))
+	}
+	for _, tr := range transitions {
+		if tr.from != PressureLevelNormal || tr.to != PressureLevelCritical || tr.stats != source.stats {
+			t.Errorf("transition %s -> %s with %s, want normal -> critical with %s", tr.from, tr.to, tr.stats, source.stats)
+		}
+		if level := PressureLevelFor(tr.stats); level != tr.to {
+			t.Errorf("transition to %s, but its snapshot is %s", tr.to, level)
+		}
+	}
+}
+
+func TestMonitorHysteresis(t *testing.T) {
+	source := &fakeSource{}
+	var transitions int
+	m := NewMonitor(time.Second, func(from, to MemoryPressureLevel, stats MemLimitRelatedStats) {
+		transitions++
+	}, WithMonitorStatsSource(source), WithHysteresis(0.9, 0.8))
+
+	steps := []struct {
+		ratio float64
+		want  MemoryPressureLevel
+	}{
+		{ratio: 0.5, want: PressureLevelNormal},
+		{ratio: 0.89, want: PressureLevelNormal},
+		{ratio: 0.9, want: PressureLevelWarning},
+		// bouncing inside the band doesn't clear the pressure.
+		{ratio: 0.85, want: PressureLevelWarning},
+		{ratio: 0.91, want: PressureLevelWarning},
+		{ratio: 0.8, want: PressureLevelWarning},
+		{ratio: 0.79, want: PressureLevelNormal},
+		// nor raises it again.
+		{ratio: 0.85, want: PressureLevelNormal},
+		{ratio: 0.89, want: PressureLevelNormal},
+	}
+	for i, step := range steps {
+		source.stats = monitorStatsForTest(step.ratio, false)
+		m.sample(i == 0)
+		if level := m.Level(); level != step.want {
+			t.Errorf("step %d: level = %s at %.2f, want %s", i, level, step.ratio, step.want)
+		}
+	}
+	// the first observation, up to warning, and back to normal.
+	if transitions != 3 {
+		t.Errorf("got %d transitions, want 3", transitions)
+	}
+}
+
+func TestMonitorHysteresisCriticalFallsToWarning(t *testing.T) {
+	source := &fakeSource{stats: monitorStatsForTest(1.1, true)}
+	m := NewMonitor(time.Second, nil, WithMonitorStatsSource(source), WithHysteresis(0.9, 0.8))
+	m.sample(true)
+	if level := m.Level(); level != PressureLevelCritical {
+		t.Fatalf("level = %s above the goal, want critical", level)
+	}
+
+	source.stats = monitorStatsForTest(0.85, false)
+	m.sample(false)
+	if level := m.Level(); level != PressureLevelWarning {
+		t.Errorf("level = %s after critical within the band, want warning", level)
+	}
+}
+
+var staleSink []byte
+
+func TestMonitorStaleDetection(t *testing.T) {
+	source := &fakeSource{stats: monitorStatsForTest(0.5, false)}
+	m := NewMonitor(time.Second, nil, WithMonitorStatsSource(source), WithStaleDetection(3))
+
+	// the source values don't move while the process keeps allocating.
+	for i := 0; i < 4; i++ {
+		staleSink = make([]byte, 1<<20)
+		m.sample(i == 0)
+		if want := i >= 3; m.Stale() != want {
+			t.Fatalf("sample %d: Stale() = %t, want %t", i, m.Stale(), want)
+		}
+	}
+
+	source.stats.HeapLive++
+	staleSink = make([]byte, 1<<20)
+	m.sample(false)
+	if m.Stale() {
+		t.Error("Stale() = true after the source values updated")
+	}
+}
+
+func TestMonitorOnSustainedCritical(t *testing.T) {
+	fired := make(chan struct{}, 10)
+	m := NewMonitor(time.Second, nil, WithMonitorStatsSource(&fakeSource{}),
+		OnSustainedCritical(3*time.Second, func() { fired <- struct{}{} }))
+
+	start := time.Now()
+	steps := []struct {
+		level MemoryPressureLevel
+		at    time.Duration
+	}{
+		{PressureLevelCritical, 0},
+		{PressureLevelCritical, 2 * time.Second},
+		// a single sample below critical restarts the count.
+		{PressureLevelWarning, 3 * time.Second},
+		{PressureLevelCritical, 4 * time.Second},
+		{PressureLevelCritical, 6 * time.Second},
+		{PressureLevelCritical, 7 * time.Second}, // fires
+		{PressureLevelCritical, 8 * time.Second}, // once per episode
+		{PressureLevelCritical, 20 * time.Second},
+	}
+	for _, step := range steps {
+		m.detectSustainedCritical(step.level, start.Add(step.at))
+	}
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("the callback was not called after 3s of sustained criticality")
+	}
+	select {
+	case <-fired:
+		t.Error("the callback was called more than once in a critical episode")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// a new episode fires again.
+	m.detectSustainedCritical(PressureLevelNormal, start.Add(21*time.Second))
+	m.detectSustainedCritical(PressureLevelCritical, start.Add(22*time.Second))
+	m.detectSustainedCritical(PressureLevelCritical, start.Add(25*time.Second))
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Error("the callback was not called for a second critical episode")
+	}
+}
+
+func TestMonitorCgroupLimitTracking(t *testing.T) {
+	setMemoryLimitForTest(t, math.MaxInt64)
+	setCgroupRootForTest(t, map[string]string{"memory.max": "1000000000"})
+
+	type change struct {
+		oldCgroupLimit, newCgroupLimit uint64
+		memoryLimit                    int64
+	}
+	var changes []change
+	m := NewMonitor(time.Second, nil, WithMonitorStatsSource(&fakeSource{}),
+		WithCgroupLimitTracking(time.Minute, 0.1, func(oldCgroupLimit, newCgroupLimit uint64, memoryLimit int64) {
+			changes = append(changes, change{oldCgroupLimit, newCgroupLimit, memoryLimit})
+		}))
+
+	// the first read only records the limit.
+	start := time.Now()
+	m.trackCgroupLimit(start)
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 || len(changes) != 0 {
+		t.Fatalf("memory limit = %d, %d changes after the first read, want it untouched", limit, len(changes))
+	}
+
+	// resized, but only read again once the interval elapsed.
+	setCgroupRootForTest(t, map[string]string{"memory.max": "2000000000"})
+	m.trackCgroupLimit(start.Add(30 * time.Second))
+	if len(changes) != 0 {
+		t.Fatalf("the cgroup was read again before the interval elapsed")
+	}
+	m.trackCgroupLimit(start.Add(time.Minute))
+	want := change{1_000_000_000, 2_000_000_000, 1_800_000_000}
+	if len(changes) != 1 || changes[0] != want {
+		t.Fatalf("changes = %+v, want [%+v]", changes, want)
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != want.memoryLimit {
+		t.Errorf("memory limit = %d, want %d", limit, want.memoryLimit)
+	}
+
+	// an unreadable cgroup leaves the memory limit as it is.
+	setCgroupRootForTest(t, nil)
+	m.trackCgroupLimit(start.Add(2 * time.Minute))
+	if limit := debug.SetMemoryLimit(-1); limit != want.memoryLimit || len(changes) != 1 {
+		t.Errorf("memory limit = %d, %d changes without a cgroup, want it unchanged", limit, len(changes))
+	}
+}
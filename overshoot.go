@@ -0,0 +1,97 @@
+package rtml
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// how often the overshoot tracker samples the live heap and the heap goal in the background.
+const overshootSampleInterval = 100 * time.Millisecond
+
+var (
+	// the largest HeapLive - HeapGoal observed since the tracking started or ResetGCOvershoot.
+	gcOvershootBytes atomic.Uint64
+
+	// the largest part of the memory limit the learned overshoot can reserve, in parts per million.
+	// 0 when WithOvershootHeadroom is not set, and the overshoot is only tracked.
+	overshootCapPPM atomic.Uint64
+
+	overshootTrackerOnce sync.Once
+)
+
+// GCOvershootBytes returns the largest overshoot of the heap goal observed, HeapLive - HeapGoal,
+// since the tracking started or since the last ResetGCOvershoot. It is 0 when the live heap never went above the goal.
+//
+// The live heap grows past the goal when the application allocates faster than a running garbage collection
+// can mark, until the collection completes. With WithOvershootHeadroom the worst overshoot is reserved
+// below the memory limit, so admission stops early enough for in flight work to survive it.
+//
+// The first call to GCOvershootBytes or ResetGCOvershoot (or Configure with WithOvershootHeadroom) starts
+// a background sampler (every 100ms). Besides the sampler, every slow path of IsMemLimitReached and every
+// GetMemLimitRelatedStats updates the overshoot, so short spikes between samples are caught when the package is busy.
+func GCOvershootBytes() uint64 {
+	startOvershootTracker()
+	return gcOvershootBytes.Load()
+}
+
+// ResetGCOvershoot forgets the learned overshoot, e.g. after a deployment changed the workload,
+// or to learn it over a window by reading GCOvershootBytes and then calling ResetGCOvershoot periodically.
+func ResetGCOvershoot() {
+	startOvershootTracker()
+	gcOvershootBytes.Store(0)
+}
+
+func startOvershootTracker() {
+	overshootTrackerOnce.Do(func() {
+		sampleOvershoot()
+		go func() {
+			ticker := time.NewTicker(overshootSampleInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				sampleOvershoot()
+			}
+		}()
+	})
+}
+
+func sampleOvershoot() {
+	if degraded.Load() || LayoutVerified() != nil {
+		return
+	}
+	defer markDegradedOnPanic()
+
+	heapGoal, ok := readHeapGoal()
+	if !ok {
+		return
+	}
+	observeOvershoot(heapGoal, runtimeGCController.heapLive.Load())
+}
+
+// records heapLive - heapGoal as the overshoot if it is larger than the current one.
+// a zero heap goal (before the controller warmed up) says nothing about the overshoot.
+func observeOvershoot(heapGoal, heapLive uint64) {
+	if heapGoal == 0 || heapLive <= heapGoal {
+		return
+	}
+	overshoot := heapLive - heapGoal
+	for {
+		current := gcOvershootBytes.Load()
+		if overshoot <= current {
+			return
+		}
+		if gcOvershootBytes.CompareAndSwap(current, overshoot) {
+			return
+		}
+	}
+}
+
+// the learned overshoot reserved below limit (the memory limit after the fixed headroom),
+// capped by WithOvershootHeadroom. 0 when the option is not set.
+func overshootHeadroom(memoryLimit, limit uint64) uint64 {
+	capPPM := overshootCapPPM.Load()
+	if capPPM == 0 {
+		return 0
+	}
+	return min(gcOvershootBytes.Load(), memoryLimit/1_000_000*capPPM, limit)
+}
@@ -0,0 +1,63 @@
+package rtml
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// how often the peak tracker samples LimitRatio in the background.
+const peakSampleInterval = 100 * time.Millisecond
+
+var (
+	// float64 bits of the highest LimitRatio observed since the tracking started or ResetPeak.
+	peakRatioBits atomic.Uint64
+
+	peakTrackerOnce sync.Once
+)
+
+// PeakLimitRatio returns the highest LimitRatio observed since the peak tracking started, or since the last ResetPeak.
+// Operators can use it to size a service by the worst memory pressure it has seen, not just the current value.
+//
+// The first call to PeakLimitRatio or ResetPeak starts a background sampler (every 100ms), so call one of them
+// once on startup to track the peak from there. Besides the sampler, every LimitRatio (and MemUtilizationRatio)
+// computed by the package, e.g. by a Monitor or GetMemoryPressureLevel, updates the peak, with a single atomic load
+// in the common case where it is not a new peak. It is safe to call concurrently.
+func PeakLimitRatio() float64 {
+	startPeakTracker()
+	return math.Float64frombits(peakRatioBits.Load())
+}
+
+// ResetPeak restarts the peak from the current LimitRatio, for windowed measurements,
+// e.g. reading PeakLimitRatio and then calling ResetPeak every minute.
+func ResetPeak() {
+	startPeakTracker()
+	peakRatioBits.Store(math.Float64bits(LimitRatio()))
+}
+
+func startPeakTracker() {
+	peakTrackerOnce.Do(func() {
+		observePeak(LimitRatio())
+		go func() {
+			ticker := time.NewTicker(peakSampleInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				observePeak(LimitRatio())
+			}
+		}()
+	})
+}
+
+// records ratio as the peak if it is higher than the current one.
+func observePeak(ratio float64) {
+	for {
+		bits := peakRatioBits.Load()
+		if ratio <= math.Float64frombits(bits) {
+			return
+		}
+		if peakRatioBits.CompareAndSwap(bits, math.Float64bits(ratio)) {
+			return
+		}
+	}
+}
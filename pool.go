@@ -0,0 +1,145 @@
+package rtml
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// returned by AdaptivePool.Submit after the pool is closed.
+var ErrPoolClosed = errors.New("rtml: pool is closed")
+
+// AdaptivePool runs submitted tasks on up to N concurrent workers,
+// and stops dispatching new tasks while the memory limit is reached.
+//
+// Tasks that are already running are not interrupted, but no new task starts until
+// IsMemLimitReached returns false again, so the amount of concurrent heavy work
+// naturally scales down under memory pressure. Submitted tasks wait in a bounded queue.
+type AdaptivePool struct {
+	source  StatsSource
+	tasks   chan func()
+	workers chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	// closed at the start of Close, to release the Submit calls blocked on a full queue.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	running sync.WaitGroup
+	done    chan struct{}
+
+	pauses      atomic.Int64
+	pausedNanos atomic.Int64
+}
+
+// counters describing how the pool reacted to memory pressure.
+type AdaptivePoolStats struct {
+	// number of times dispatching was paused because the memory limit was reached.
+	Pauses int64
+
+	// total time dispatching was paused.
+	PausedDuration time.Duration
+
+	// number of tasks waiting in the queue.
+	Queued int
+}
+
+// NewAdaptivePool starts a pool with up to workers concurrent tasks,
+// and a queue that holds up to queueSize submitted tasks before Submit blocks.
+func NewAdaptivePool(workers int, queueSize int) *AdaptivePool {
+	return NewAdaptivePoolFor(workers, queueSize, runtimeStatsSource{})
+}
+
+// NewAdaptivePoolFor creates a pool that pauses dispatching while source reports the limit reached,
+// instead of the go runtime. See StatsSource.
+func NewAdaptivePoolFor(workers int, queueSize int, source StatsSource) *AdaptivePool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &AdaptivePool{
+		source:  sourceOrRuntime(source),
+		tasks:   make(chan func(), queueSize),
+		workers: make(chan struct{}, workers),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	go p.dispatch()
+	return p
+}
+
+// Submit queues task for execution, blocking while the queue is full.
+// It returns ErrPoolClosed if the pool was closed, including while it was blocked.
+func (p *AdaptivePool) Submit(task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-p.closing:
+		return ErrPoolClosed
+	}
+}
+
+// Close stops accepting new tasks, and waits for all queued and running tasks to complete.
+// Submit calls blocked on a full queue return ErrPoolClosed right away, but the queued tasks
+// still only start while the memory limit is not reached, so Close waits for them as long as it is.
+func (p *AdaptivePool) Close() {
+	p.closeOnce.Do(func() { close(p.closing) })
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.done
+		return
+	}
+	p.closed = true
+	close(p.tasks)
+	p.mu.Unlock()
+
+	<-p.done
+}
+
+// Stats returns the pool pressure counters.
+func (p *AdaptivePool) Stats() AdaptivePoolStats {
+	return AdaptivePoolStats{
+		Pauses:         p.pauses.Load(),
+		PausedDuration: time.Duration(p.pausedNanos.Load()),
+		Queued:         len(p.tasks),
+	}
+}
+
+func (p *AdaptivePool) dispatch() {
+	defer close(p.done)
+	defer p.running.Wait()
+
+	for task := range p.tasks {
+		// consult the heuristic before starting each task, and hold it while the limit is reached.
+		if p.source.Reached() {
+			start := time.Now()
+			p.pauses.Add(1)
+			_ = waitWhile(context.Background(), p.source.Reached)
+			p.pausedNanos.Add(int64(time.Since(start)))
+		}
+
+		p.workers <- struct{}{}
+		p.running.Add(1)
+		go func(task func()) {
+			defer func() {
+				<-p.workers
+				p.running.Done()
+			}()
+			task()
+		}(task)
+	}
+}
@@ -0,0 +1,44 @@
+package rtml
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptivePoolCloseReleasesBlockedSubmit(t *testing.T) {
+	p := NewAdaptivePool(1, 0)
+
+	// occupy the only worker, and the dispatcher with a second task, so the next Submit blocks.
+	release := make(chan struct{})
+	if err := p.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit() = %v", err)
+	}
+	if err := p.Submit(func() {}); err != nil {
+		t.Fatalf("Submit() = %v", err)
+	}
+	blocked := make(chan error)
+	go func() { blocked <- p.Submit(func() {}) }()
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("blocked Submit() = %v, want ErrPoolClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close did not release the blocked Submit")
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close did not return after the running tasks completed")
+	}
+}
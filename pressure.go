@@ -0,0 +1,117 @@
+package rtml
+
+import "math"
+
+// MemoryPressureLevel is a coarse classification of how close the process is
+// to its memory limit. It is derived from the same values IsMemLimitReached uses,
+// with an additional "warning" band below the limit so callers can react
+// (log, shed optional work, evict caches) before admission starts failing.
+type MemoryPressureLevel int
+
+const (
+	// memory usage is comfortably below the limit.
+	PressureLevelNormal MemoryPressureLevel = iota
+
+	// memory usage is getting close to the limit, but new work can still be accepted.
+	PressureLevelWarning
+
+	// memory limit is reached (IsMemLimitReached returns true).
+	PressureLevelCritical
+)
+
+func (l MemoryPressureLevel) String() string {
+	switch l {
+	case PressureLevelNormal:
+		return "normal"
+	case PressureLevelWarning:
+		return "warning"
+	case PressureLevelCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// MemUtilizationRatio returns how much of the memory limit is in use,
+// computed as (MappedReady - HeapFree) / MemoryLimit.
+//
+// 0 means nothing is used, 1 means the limit is fully used.
+// The value can be above 1 since GOMEMLIMIT is a soft limit.
+// When no limit is set, the value is 0.
+func MemUtilizationRatio() float64 {
+	memoryLimit := runtimeGCController.memoryLimit.Load()
+	mappedReady := runtimeGCController.mappedReady.Load()
+	heapFree := runtimeGCController.heapFree.load()
+	ratio := utilizationRatio(uint64(memoryLimit), mappedReady, heapFree)
+	observePeak(ratio)
+	return ratio
+}
+
+// LimitRatio returns how close the process is to the memory limit, (MappedReady - HeapFree) / MemoryLimit.
+// It is the same value as MemUtilizationRatio, named to contrast it with HeapGoalRatio.
+//
+// This is the right signal for admission: it measures the memory that counts towards the limit
+// (and eventually towards an OOM kill), whatever the garbage collector does next.
+func LimitRatio() float64 {
+	return MemUtilizationRatio()
+}
+
+// HeapGoalRatio returns how close the live heap is to the heap goal, HeapLive / HeapGoal,
+// that is how close the next garbage collection is. It is 0 when the heap goal can't be read.
+//
+// This is the right signal for GC tuning (e.g. GOGC): a value near 1 means a collection is imminent,
+// and a value above 1 means the collector can't keep up. On its own it says nothing about the memory limit:
+// the ratio cycles towards 1 between every collection, even with plenty of memory left.
+func HeapGoalRatio() float64 {
+	heapGoal, ok := readHeapGoal()
+	if !ok || heapGoal == 0 {
+		return 0
+	}
+	return float64(runtimeGCController.heapLive.Load()) / float64(heapGoal)
+}
+
+func utilizationRatio(memoryLimit, mappedReady, heapFree uint64) float64 {
+	if memoryLimit == 0 || memoryLimit == noMemoryLimit {
+		return 0
+	}
+	// values are read one by one and might be inconsistent,
+	// avoid underflow in case heap free was updated after mapped ready.
+	if heapFree >= mappedReady {
+		return 0
+	}
+	return float64(mappedReady-heapFree) / float64(memoryLimit)
+}
+
+// GetMemoryPressureLevel returns the current pressure level of the process.
+//
+// PressureLevelCritical is returned exactly when IsMemLimitReached returns true,
+// PressureLevelWarning when the utilization ratio is above 80% of the limit (see WithLowWatermark),
+// and PressureLevelNormal otherwise.
+func GetMemoryPressureLevel() MemoryPressureLevel {
+	if IsMemLimitReached() {
+		return PressureLevelCritical
+	}
+	if MemUtilizationRatio() >= math.Float64frombits(warningRatioBits.Load()) {
+		return PressureLevelWarning
+	}
+	return PressureLevelNormal
+}
+
+// LimitRatio returns the LimitRatio of the snapshot, (MappedReady - HeapFree) / MemoryLimit,
+// or 0 when no limit is set.
+func (s MemLimitRelatedStats) LimitRatio() float64 {
+	return utilizationRatio(s.MemoryLimit, s.MappedReady, s.HeapFree)
+}
+
+// PressureLevelFor returns the pressure level for a stats snapshot, the way GetMemoryPressureLevel
+// does from the runtime, using MemLimitReachedFor for PressureLevelCritical.
+// It lets a single read feed both the ratio and the level, e.g. when annotating traces or logs.
+func PressureLevelFor(stats MemLimitRelatedStats) MemoryPressureLevel {
+	if MemLimitReachedFor(stats) {
+		return PressureLevelCritical
+	}
+	if stats.LimitRatio() >= math.Float64frombits(warningRatioBits.Load()) {
+		return PressureLevelWarning
+	}
+	return PressureLevelNormal
+}
@@ -0,0 +1,62 @@
+package rtml
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// bounds of the backoff between pressure checks while a throttled reader is paused.
+const (
+	throttleMinBackoff = time.Millisecond
+	throttleMaxBackoff = 100 * time.Millisecond
+)
+
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	check func() bool
+}
+
+// ThrottledReader wraps r so that Read blocks while check returns true,
+// and only then delegates to the underlying reader.
+// When check is nil, IsMemLimitReached is used.
+//
+// In streaming ingest pipelines this naturally slows down consumers (and, through the
+// transport, the senders) that would otherwise keep buffering data while memory is tight.
+// While paused, check is polled with an exponential backoff bounded to 100ms.
+func ThrottledReader(r io.Reader, check func() bool) io.Reader {
+	return ThrottledReaderContext(context.Background(), r, check)
+}
+
+// ThrottledReaderContext is like ThrottledReader, but a paused Read gives up
+// and returns the context error once ctx is done.
+func ThrottledReaderContext(ctx context.Context, r io.Reader, check func() bool) io.Reader {
+	if check == nil {
+		check = IsMemLimitReached
+	}
+	return &throttledReader{ctx: ctx, r: r, check: check}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if err := waitWhile(t.ctx, t.check); err != nil {
+		return 0, err
+	}
+	return t.r.Read(p)
+}
+
+// blocks while check returns true, polling it with a bounded exponential backoff.
+func waitWhile(ctx context.Context, check func() bool) error {
+	backoff := throttleMinBackoff
+	for check() {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, throttleMaxBackoff)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package rtml
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ReadinessHandler returns a handler suitable for a kubernetes readinessProbe (httpGet).
+//
+// It responds with 200 while the current pressure level is below level,
+// and with 503 once the pressure reaches it, so the orchestrator stops routing
+// new traffic to a pod that is about to run out of memory.
+// The response body states the current level and utilization, which shows up in probe failure events.
+//
+//	http.Handle("/readyz", rtml.ReadinessHandler(rtml.PressureLevelCritical))
+func ReadinessHandler(level MemoryPressureLevel) http.HandlerFunc {
+	return ReadinessHandlerFor(level, runtimeStatsSource{})
+}
+
+// ReadinessHandlerFor is ReadinessHandler, reporting the pressure of source instead of the go runtime. See StatsSource.
+func ReadinessHandlerFor(level MemoryPressureLevel, source StatsSource) http.HandlerFunc {
+	source = sourceOrRuntime(source)
+	return func(w http.ResponseWriter, r *http.Request) {
+		current := pressureLevelOf(source)
+		utilization := utilizationRatioOf(source)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if current >= level {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "not ready: memory pressure level is %s (threshold %s), utilization %.1f%%\n",
+				current, level, utilization*100)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "ready: memory pressure level is %s (threshold %s), utilization %.1f%%\n",
+			current, level, utilization*100)
+	}
+}
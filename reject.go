@@ -0,0 +1,81 @@
+package rtml
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// utilization ratios between which ShouldReject ramps the rejection probability from 0 to 1.
+type rejectWatermarks struct {
+	low  float64
+	high float64
+}
+
+var currentRejectWatermarks atomic.Pointer[rejectWatermarks]
+
+func init() {
+	currentRejectWatermarks.Store(&rejectWatermarks{low: 0.8, high: 1.0})
+}
+
+// SetRejectWatermarks configures the utilization ratios used by ShouldReject.
+// Below low nothing is rejected, above high everything is rejected, and in between
+// the rejection probability grows linearly.
+// The defaults are 0.8 and 1.0 (start shedding at 80% of the memory limit, reject all at the limit).
+//
+// It is safe to call concurrently with ShouldReject.
+func SetRejectWatermarks(low, high float64) error {
+	if !(low >= 0 && low < high) {
+		return fmt.Errorf("rtml: invalid reject watermarks, expected 0 <= low < high, got low=%v high=%v", low, high)
+	}
+	currentRejectWatermarks.Store(&rejectWatermarks{low: low, high: high})
+	return nil
+}
+
+// RejectProbability returns the probability (0 to 1) with which ShouldReject currently rejects,
+// based on MemUtilizationRatio and the configured watermarks.
+func RejectProbability() float64 {
+	return rejectProbabilityFor(MemUtilizationRatio(), currentRejectWatermarks.Load())
+}
+
+// RejectProbabilityFor is RejectProbability, from the utilization of source instead of the go runtime. See StatsSource.
+func RejectProbabilityFor(source StatsSource) float64 {
+	return rejectProbabilityFor(utilizationRatioOf(sourceOrRuntime(source)), currentRejectWatermarks.Load())
+}
+
+func rejectProbabilityFor(ratio float64, w *rejectWatermarks) float64 {
+	switch {
+	case ratio <= w.low:
+		return 0
+	case ratio >= w.high:
+		return 1
+	default:
+		return (ratio - w.low) / (w.high - w.low)
+	}
+}
+
+// ShouldReject is a graceful alternative to IsMemLimitReached.
+// Instead of flipping from accepting everything to rejecting everything at the limit,
+// it rejects a randomly selected, growing fraction of the work as memory utilization rises
+// (see SetRejectWatermarks), which gives senders smoother backpressure.
+//
+// It is thread safe and cheap: a few atomic loads and a call to the runtime random source.
+func ShouldReject() bool {
+	return rejectWithProbability(RejectProbability())
+}
+
+// ShouldRejectFor is ShouldReject, from the utilization of source instead of the go runtime. See StatsSource.
+func ShouldRejectFor(source StatsSource) bool {
+	return rejectWithProbability(RejectProbabilityFor(source))
+}
+
+func rejectWithProbability(p float64) bool {
+	switch p {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		return rand.Float64() < p
+	}
+}
@@ -0,0 +1,69 @@
+package rtml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRejectProbabilityFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		mappedReady uint64
+		want        float64
+	}{
+		{name: "below the low watermark", mappedReady: 500, want: 0},
+		{name: "at the low watermark", mappedReady: 800, want: 0},
+		{name: "between the watermarks", mappedReady: 900, want: 0.5},
+		{name: "at the high watermark", mappedReady: 1000, want: 1},
+		{name: "above the limit", mappedReady: 1200, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: tt.mappedReady}}
+			if got := RejectProbabilityFor(source); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RejectProbabilityFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRejectFor(t *testing.T) {
+	below := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 500}}
+	above := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1000}}
+	between := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 900}}
+
+	rejected := 0
+	for i := 0; i < 1000; i++ {
+		if ShouldRejectFor(below) {
+			t.Fatal("ShouldRejectFor() = true below the low watermark")
+		}
+		if !ShouldRejectFor(above) {
+			t.Fatal("ShouldRejectFor() = false at the high watermark")
+		}
+		if ShouldRejectFor(between) {
+			rejected++
+		}
+	}
+	// half way between the watermarks, about half is rejected.
+	if rejected < 350 || rejected > 650 {
+		t.Errorf("rejected %d of 1000 half way between the watermarks, want about 500", rejected)
+	}
+}
+
+func TestSetRejectWatermarks(t *testing.T) {
+	previous := currentRejectWatermarks.Load()
+	t.Cleanup(func() { currentRejectWatermarks.Store(previous) })
+
+	for _, w := range []struct{ low, high float64 }{{0.9, 0.9}, {-0.1, 1}, {math.NaN(), 1}} {
+		if err := SetRejectWatermarks(w.low, w.high); err == nil {
+			t.Errorf("SetRejectWatermarks(%v, %v) accepted invalid watermarks", w.low, w.high)
+		}
+	}
+	if err := SetRejectWatermarks(0.5, 0.7); err != nil {
+		t.Fatal(err)
+	}
+	source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 600}}
+	if got := RejectProbabilityFor(source); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("RejectProbabilityFor() = %v with watermarks 0.5 and 0.7, want 0.5", got)
+	}
+}
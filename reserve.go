@@ -0,0 +1,77 @@
+package rtml
+
+// TryReserve reports whether sizeBytes more memory can be afforded, i.e. whether the memory in use
+// (MappedReady - HeapFree) plus sizeBytes stays within the memory limit (minus the headroom of WithHeadroomFraction).
+// It does not allocate anything.
+//
+// It is a forward looking check ("can I afford this buffer?"), complementing the reactive IsMemLimitReached,
+// for components that can decline to grow, like caches:
+//
+//	if rtml.TryReserve(uint64(len(value))) {
+//		cache.Add(key, value)
+//	}
+//
+// The result is advisory: nothing is actually reserved, so concurrent callers may all succeed
+// and together go past the limit. Reaching exactly the limit is allowed.
+// When no memory limit is set it always returns true.
+func TryReserve(sizeBytes uint64) bool {
+	memoryLimit := runtimeGCController.memoryLimit.Load()
+	if memoryLimit == noMemoryLimit {
+		return true
+	}
+	mappedReady := runtimeGCController.mappedReady.Load()
+	heapFree := runtimeGCController.heapFree.load()
+	return canReserve(effectiveMemoryLimit(uint64(memoryLimit)), mappedReady, heapFree, sizeBytes)
+}
+
+func canReserve(limit, mappedReady, heapFree, sizeBytes uint64) bool {
+	used := uint64(0)
+	// values are read one by one, heap free can be ahead of mapped ready.
+	if mappedReady > heapFree {
+		used = mappedReady - heapFree
+	}
+	if used > limit {
+		return false
+	}
+	// compared as the room left, so a huge sizeBytes can't overflow.
+	return sizeBytes <= limit-used
+}
+
+// returned by AdmitRequest when the memory limit is not reached, but the estimated cost of the request doesn't fit below it.
+const ReasonInsufficientRoom = "insufficient_room"
+
+// AdmitRequest reports whether a request expected to allocate about estimatedBytes should be admitted,
+// and why, combining the current limit check (MemLimitStatus) with the forward looking check of TryReserve.
+// Handlers that know their rough memory cost (e.g. decompressing a payload of known size) can reject
+// the requests that would push the process over the limit, while still admitting the cheap ones:
+//
+//	if ok, reason := rtml.AdmitRequest(uint64(r.ContentLength) * 4); !ok {
+//		rejected.WithLabelValues(reason).Inc()
+//		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+//		return
+//	}
+//
+// When the limit is reached, the reason is the one of MemLimitStatus (e.g. ReasonAboveGoal).
+// When it is not, but estimatedBytes doesn't fit, the reason is ReasonInsufficientRoom.
+// An estimatedBytes of 0 skips the TryReserve check, so a request with no cost is admitted exactly when
+// IsMemLimitReached is false, including above the limit while the live heap is below the heap goal.
+// Admitted requests get the reason of MemLimitStatus as well (e.g. ReasonBelowMapped), so the reasons
+// can label metrics of both outcomes.
+//
+// Like TryReserve, the result is advisory and racy: nothing is reserved, concurrent requests are checked against
+// the same room and may all be admitted, and the estimate itself is only as good as the caller's guess.
+func AdmitRequest(estimatedBytes uint64) (bool, string) {
+	reached, reason := MemLimitStatus()
+	return admitRequest(reached, reason, estimatedBytes, TryReserve)
+}
+
+// the decision of AdmitRequest, from the result of MemLimitStatus.
+func admitRequest(reached bool, reason string, estimatedBytes uint64, tryReserve func(uint64) bool) (bool, string) {
+	if reached {
+		return false, reason
+	}
+	if estimatedBytes != 0 && !tryReserve(estimatedBytes) {
+		return false, ReasonInsufficientRoom
+	}
+	return true, reason
+}
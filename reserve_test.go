@@ -0,0 +1,77 @@
+package rtml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCanReserve(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		limit, mappedReady, heapFree, request uint64
+		want                                  bool
+	}{
+		{name: "fits", limit: 100, mappedReady: 50, request: 10, want: true},
+		{name: "reaches the limit exactly", limit: 100, mappedReady: 60, heapFree: 10, request: 50, want: true},
+		{name: "past the limit", limit: 100, mappedReady: 60, heapFree: 10, request: 51, want: false},
+		{name: "heap free is available", limit: 100, mappedReady: 120, heapFree: 40, request: 20, want: true},
+		{name: "already above the limit", limit: 100, mappedReady: 120, request: 0, want: false},
+		{name: "heap free ahead of mapped ready", limit: 100, mappedReady: 10, heapFree: 20, request: 100, want: true},
+		{name: "huge request", limit: 100, mappedReady: 50, request: math.MaxUint64, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canReserve(tt.limit, tt.mappedReady, tt.heapFree, tt.request); got != tt.want {
+				t.Errorf("canReserve(%d, %d, %d, %d) = %t, want %t", tt.limit, tt.mappedReady, tt.heapFree, tt.request, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTryReserve(t *testing.T) {
+	setMemoryLimitForTest(t, math.MaxInt64)
+	if !TryReserve(math.MaxUint64) {
+		t.Errorf("TryReserve() = false without a memory limit")
+	}
+
+	limit := GetMemLimitRelatedStats().MappedReady + 64<<20
+	setMemoryLimitForTest(t, int64(limit))
+	if !TryReserve(0) || !TryReserve(1<<20) {
+		t.Errorf("TryReserve() refused a small buffer below the memory limit")
+	}
+	if TryReserve(limit) {
+		t.Errorf("TryReserve() accepted a buffer as large as the memory limit")
+	}
+}
+
+func TestAdmitRequest(t *testing.T) {
+	Warmup()
+	limit := GetMemLimitRelatedStats().MappedReady + 64<<20
+	setMemoryLimitForTest(t, int64(limit))
+
+	if admitted, reason := AdmitRequest(1 << 20); !admitted || reason != ReasonBelowMapped {
+		t.Errorf("AdmitRequest(1MB) = %t, %q, want true, %q", admitted, reason, ReasonBelowMapped)
+	}
+	if admitted, reason := AdmitRequest(limit); admitted || reason != ReasonInsufficientRoom {
+		t.Errorf("AdmitRequest(limit) = %t, %q, want false, %q", admitted, reason, ReasonInsufficientRoom)
+	}
+}
+
+func TestAdmitRequestAboveLimitBelowGoal(t *testing.T) {
+	// memory in use is above the limit, but the live heap is below the goal, so the limit is not reached.
+	stats := MemLimitRelatedStats{MemoryLimit: 100, MappedReady: 120, HeapGoal: 100, HeapLive: 90}
+	reached, reason := MemLimitReachedFor(stats), memLimitReason(snapshotInputs(stats, stats.MemoryLimit))
+	if reached || reason != ReasonBelowGoal {
+		t.Fatalf("MemLimitReachedFor(%s) = %t, %q, want false, %q", stats, reached, reason, ReasonBelowGoal)
+	}
+	tryReserve := func(sizeBytes uint64) bool {
+		return canReserve(stats.MemoryLimit, stats.MappedReady, stats.HeapFree, sizeBytes)
+	}
+
+	if admitted, got := admitRequest(reached, reason, 0, tryReserve); !admitted || got != ReasonBelowGoal {
+		t.Errorf("admitRequest(0) = %t, %q, want true, %q", admitted, got, ReasonBelowGoal)
+	}
+	if admitted, got := admitRequest(reached, reason, 1, tryReserve); admitted || got != ReasonInsufficientRoom {
+		t.Errorf("admitRequest(1) = %t, %q, want false, %q", admitted, got, ReasonInsufficientRoom)
+	}
+}
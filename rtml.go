@@ -1,18 +1,10 @@
 package rtml
 
 import (
-	"sync/atomic"
+	"math"
 	_ "unsafe"
 )
 
-// miror the types from go runtime which uses sysMemStat.
-// https://github.com/golang/go/blob/44c5956bf7454ca178c596eb87578ea61d6c9dee/src/runtime/mstats.go#L643
-type sysMemStat uint64
-
-func (s *sysMemStat) load() uint64 {
-	return atomic.LoadUint64((*uint64)(s))
-}
-
 // using go linkname so we can read the internal values that the
 // garbage collector controller is using in real time and cheaply.
 // this allows us to invoke it in a high frequency without the overhead
@@ -31,54 +23,8 @@ var runtimeGCController gcControllerState
 //go:linkname runtimeHeapGoal runtime.(*gcControllerState).heapGoal
 func runtimeHeapGoal(*gcControllerState) uint64
 
-// following struct is a mirror of the exact struct used by the go runtime.
-// notice that it must match exactly (field order and types).
-// if go ever changes the internal struct, this need to be updated as well,
-// or we can get invalid values when accessing those fields.
-type gcControllerState struct {
-	gcPercent                  atomic.Int32
-	memoryLimit                atomic.Int64
-	heapMinimum                uint64
-	runway                     atomic.Uint64
-	consMark                   float64
-	lastConsMark               [4]float64
-	gcPercentHeapGoal          atomic.Uint64
-	sweepDistMinTrigger        atomic.Uint64
-	triggered                  uint64
-	lastHeapGoal               uint64
-	heapLive                   atomic.Uint64
-	heapScan                   atomic.Uint64
-	lastHeapScan               uint64
-	lastStackScan              atomic.Uint64
-	maxStackScan               atomic.Uint64
-	globalsScan                atomic.Uint64
-	heapMarked                 uint64
-	heapScanWork               atomic.Int64
-	stackScanWork              atomic.Int64
-	globalsScanWork            atomic.Int64
-	bgScanCredit               atomic.Int64
-	assistTime                 atomic.Int64
-	dedicatedMarkTime          atomic.Int64
-	fractionalMarkTime         atomic.Int64
-	idleMarkTime               atomic.Int64
-	markStartTime              int64
-	dedicatedMarkWorkersNeeded atomic.Int64
-	idleMarkWorkers            atomic.Uint64
-	assistWorkPerByte          atomic.Uint64 // This was Float64 originally (from go internals). not used so don't matter
-	assistBytesPerWork         atomic.Uint64 // This was Float64 originally (from go internals). not used so don't matter
-	fractionalUtilizationGoal  float64
-
-	// fields used for memory limiting goal calculation
-	heapInUse    sysMemStat
-	heapReleased sysMemStat
-	heapFree     sysMemStat
-	totalAlloc   atomic.Uint64
-	totalFree    atomic.Uint64
-	mappedReady  atomic.Uint64
-
-	test bool
-	_    [64]byte
-}
+// the value of the runtime memory limit when GOMEMLIMIT is not set (or set to "off").
+const noMemoryLimit = math.MaxInt64
 
 // Call this function to check if the memory limit of the process is reached
 // and react according to the boolean return value.
@@ -103,36 +49,220 @@ type gcControllerState struct {
 //
 // It is important to understand that this function is heuristic in it's nature,
 // and is expected to produce correct results most of the time, but not always.
+//
+// When no memory limit is configured, the limit is never reached and the function always returns "false".
+// Until the garbage collector controller is initialized (see ControllerReady), it returns "false" as well.
+//
+// If reading the runtime internals panics (which can only happen on a go version that changed them in an
+// unexpected way), the panic is recovered rather than crashing the process, and IsSupported starts returning false.
+// In that case, and when the stats are inconsistent, the result follows SetConservative.
 func IsMemLimitReached() bool {
 
+	// the common case (no limit, or mapped memory below the limit) costs just
+	// two atomic loads and a compare, with a small stack frame.
+	// the less common checks are moved to a separate function, so they don't weigh on this path.
+	memoryLimit := runtimeGCController.memoryLimit.Load()
+	mappedReady := runtimeGCController.mappedReady.Load()
+
 	// fast check - if the mapped memory is below the limit, we are good.
 	// this check is expected to cover most cases (normal operationwhen memory limit is not reached)
+	//
+	// no limit => never reached.
+	// when GOMEMLIMIT is not set, the runtime uses math.MaxInt64 as the limit,
+	// which is always above mapped ready, but it is checked explicitly in the slow path as well.
+	// the limit is lowered by the configured headroom (see WithHeadroomFraction), if any.
+	if effectiveMemoryLimit(uint64(memoryLimit)) > mappedReady {
+		return false
+	}
+
+	return isMemLimitReachedSlow(memoryLimit, mappedReady)
+}
+
+// the rest of IsMemLimitReached, for when mapped memory is at or above the limit.
+//
+//go:noinline
+func isMemLimitReachedSlow(memoryLimit int64, mappedReady uint64) bool {
+	return reachedForReason(memLimitSlowPathReason(memoryLimit, effectiveMemoryLimit(uint64(memoryLimit)), mappedReady))
+}
+
+// reasons returned by MemLimitStatus, one per check made by IsMemLimitReached.
+const (
+	// mapped ready memory is below the limit (the fast path), or no limit is set.
+	ReasonBelowMapped = "below_mapped"
+
+	// mapped ready memory is above the limit, but after deducting heap free bytes it is below.
+	ReasonBelowAfterFree = "below_after_free"
+
+	// memory is above the limit, but the live heap is below the heap goal.
+	ReasonBelowGoal = "below_goal"
+
+	// memory is above the limit, and the live heap is above the heap goal => limit reached.
+	ReasonAboveGoal = "above_goal"
+
+	// memory in use is within the headroom reserved with WithHeadroomFraction (and WithOvershootHeadroom),
+	// or above the limit while a headroom is reserved => limit reached.
+	ReasonHeadroom = "headroom"
+
+	// the runtime internals can't be trusted: the layout verification failed,
+	// or reading them panicked. the result follows SetConservative.
+	ReasonDegraded = "degraded"
+
+	// memory is above the limit, but the stats contradict each other.
+	// the result follows SetConservative.
+	ReasonInconsistent = "inconsistent"
+
+	// memory is above the limit, but the garbage collector controller is not initialized yet
+	// (see ControllerReady), so the stats can't be trusted => not reached.
+	ReasonWarmingUp = "warming_up"
+
+	// memory in use is above the limit, and the heap goal is 0 after the controller warmed up, so it can't
+	// be compared with the live heap. the decision falls back to the mapped ready memory alone => limit reached.
+	ReasonGoalUnavailable = "goal_unavailable"
+)
+
+// MemLimitStatus is like IsMemLimitReached, but also returns which check made the decision,
+// as one of the Reason* constants.
+//
+// It is useful for observability, to understand why admission flipped.
+// IsMemLimitReached remains the cheapest way to get just the boolean.
+func MemLimitStatus() (reached bool, reason string) {
 	memoryLimit := runtimeGCController.memoryLimit.Load()
 	mappedReady := runtimeGCController.mappedReady.Load()
-	if uint64(memoryLimit) > mappedReady {
-		return false
+	if effectiveMemoryLimit(uint64(memoryLimit)) > mappedReady {
+		return false, ReasonBelowMapped
+	}
+
+	reason = memLimitSlowPathReason(memoryLimit, effectiveMemoryLimit(uint64(memoryLimit)), mappedReady)
+	return reachedForReason(reason), reason
+}
+
+// MemLimitReachedFor makes the same decision as IsMemLimitReached, from a stats snapshot instead of the live runtime values.
+//
+// Callers that already hold a snapshot (e.g. from GetMemLimitRelatedStats, a Monitor callback or WatchMemLimitStats)
+// can use it to avoid reading the runtime again, and tests can use it to exercise every branch of the heuristic
+// deterministically, with hand made values. The configured headroom and SetConservative are applied the same way.
+//
+// The decision is only as good as the snapshot: one from GetMemLimitRelatedStats is read field by field,
+// like IsMemLimitReached reads them, and might be inconsistent, while an older or decoded snapshot is stale.
+// The decision depends only on the snapshot (and the configuration): the controller is considered warmed up
+// when the snapshot has a non-zero HeapGoal and HeapLive, regardless of the history of the process. So a snapshot
+// with a zero HeapGoal or HeapLive is never reached (ReasonWarmingUp), while IsMemLimitReached treats the same
+// values as ReasonGoalUnavailable or ReasonInconsistent once the process warmed up.
+// IsMemLimitReached remains the cheap default for live checks.
+func MemLimitReachedFor(stats MemLimitRelatedStats) bool {
+	return reachedForReason(memLimitReason(snapshotInputs(stats, effectiveMemoryLimit(stats.MemoryLimit))))
+}
+
+// the inputs of the decision for a snapshot, compared against effectiveLimit.
+func snapshotInputs(stats MemLimitRelatedStats, effectiveLimit uint64) memLimitInputs {
+	return memLimitInputs{
+		memoryLimit:    stats.MemoryLimit,
+		effectiveLimit: effectiveLimit,
+		mappedReady:    stats.MappedReady,
+		heapFree:       stats.HeapFree,
+		heapGoal:       stats.HeapGoal,
+		heapLive:       stats.HeapLive,
+		warmedUp:       stats.HeapGoal != 0 && stats.HeapLive != 0,
+	}
+}
+
+// the decision of IsMemLimitReached from the live runtime values, once mappedReady reached effectiveLimit.
+func memLimitSlowPathReason(memoryLimit int64, effectiveLimit uint64, mappedReady uint64) (reason string) {
+	if memoryLimit == noMemoryLimit {
+		return ReasonBelowMapped
+	}
+
+	if degraded.Load() || LayoutVerified() != nil {
+		return ReasonDegraded
+	}
+	// returned if any of the reads below panics.
+	reason = ReasonDegraded
+	defer markDegradedOnPanic()
+
+	heapGoal, ok := readHeapGoal()
+	if !ok {
+		return ReasonDegraded
+	}
+	heapLive := runtimeGCController.heapLive.Load()
+	observeOvershoot(heapGoal, heapLive)
+
+	return memLimitReason(memLimitInputs{
+		memoryLimit:    uint64(memoryLimit),
+		effectiveLimit: effectiveLimit,
+		mappedReady:    mappedReady,
+		heapFree:       runtimeGCController.heapFree.load(),
+		heapGoal:       heapGoal,
+		heapLive:       heapLive,
+		warmedUp:       observeControllerWarmUp(heapGoal, heapLive),
+	})
+}
+
+// the values the decision of IsMemLimitReached is made from,
+// read from the runtime by the live path, and built directly to replay a state deterministically.
+type memLimitInputs struct {
+	memoryLimit uint64
+
+	// the memory limit after deducting the configured headroom.
+	effectiveLimit uint64
+
+	mappedReady uint64
+	heapFree    uint64
+	heapGoal    uint64
+	heapLive    uint64
+
+	// whether the garbage collector controller was initialized (see ControllerReady).
+	warmedUp bool
+}
+
+// memLimitReason is the decision of IsMemLimitReached, as a pure function of its inputs.
+// It returns the Reason* constant of the check that made the decision, see reachedForReason.
+func memLimitReason(in memLimitInputs) string {
+	// no limit => never reached.
+	if in.effectiveLimit > in.mappedReady || in.memoryLimit == noMemoryLimit {
+		return ReasonBelowMapped
 	}
 
 	// any bytes in heap free are accounted for in mappedReady,
 	// but is available space to make new allocations.
-	heapFree := runtimeGCController.heapFree.load()
-	if uint64(memoryLimit) > (mappedReady - heapFree) {
-		return false
+	if in.heapFree > in.mappedReady {
+		// free heap memory is part of the mapped ready memory, it can't be larger.
+		return ReasonInconsistent
+	}
+	used := in.mappedReady - in.heapFree
+	if in.effectiveLimit > used {
+		return ReasonBelowAfterFree
+	}
+	if in.effectiveLimit < in.memoryLimit {
+		// a headroom is reserved, and memory in use is within it or already above the limit itself.
+		// the heap goal check below is not applied here: past the headroom the limit stays reached,
+		// so a process above the limit is never reported as less pressured than one within the headroom.
+		return ReasonHeadroom
+	}
+
+	if !in.warmedUp {
+		// early in the process life, before the controller values were set for the first time.
+		return ReasonWarmingUp
+	}
+	if in.heapLive == 0 {
+		// memory is above the limit, yet nothing is live on the heap.
+		return ReasonInconsistent
+	}
+	if in.heapGoal == 0 {
+		// the goal is not meaningful (e.g. the linkname misbehaves), and the live heap would always be above it.
+		// rely on the mapped ready memory only, which stops reporting reached once memory goes back below the limit.
+		return ReasonGoalUnavailable
 	}
 
 	// this is the "correct" check to make (which follows what go runtime is doing).
 	// it will compare the heap live with the heap goal.
 	// if we are above the goal, it means a GC cycle could not lower the memory limit to acceptable level.
-	heapGoal := runtimeHeapGoal(&runtimeGCController)
-	heapLive := runtimeGCController.heapLive.Load()
-
-	if heapLive < heapGoal {
+	if in.heapLive < in.heapGoal {
 		// we are below the goal, we are good, no garbage collection is needed.
-		return false
+		return ReasonBelowGoal
 	}
 
 	// live heap is above the goal => we are not able to make new allocations safely.
-	return true
+	return ReasonAboveGoal
 }
 
 // handy for debugging, troubleshooting, or gaining deep insights into the memory limiting state of the application.
@@ -163,6 +293,18 @@ type MemLimitRelatedStats struct {
 	// not used by the heap (can be used for future allocations, or freed back to the OS)
 	HeapFree uint64
 
+	// memory of the heap that was returned to the OS (scavenged), but is still mapped in the address space.
+	// it is not resident, and the runtime removes it from MappedReady when releasing it,
+	// so it does not count towards the memory limit (nor the container limit) until it is reused.
+	// a large value means MappedReady dropped because of the scavenger, and not because the heap shrank.
+	HeapReleased uint64
+
+	// memory of the spans currently holding objects (in-use spans), counted in whole spans.
+	// unlike HeapLive, the pacer's estimate of the live heap that is only refreshed by GC cycles and span refills,
+	// it follows every span that is allocated or swept, and includes the unused slots of the spans.
+	// comparing it with NetAllocatedBytes shows how much of the heap is lost to fragmentation (see Fragmentation).
+	HeapInUse uint64
+
 	// TotalAlloc and TotalFree are monotonic counters that are incremented
 	// whenever we allocate or free memory (in "span" resolution).
 	// their subtraction is the amount of allocated memory.
@@ -172,21 +314,76 @@ type MemLimitRelatedStats struct {
 	TotalFree  uint64
 }
 
+// NetAllocatedBytes returns TotalAlloc - TotalFree, the memory allocated and not yet freed,
+// or 0 if TotalFree is ahead of TotalAlloc (the counters are read one by one).
+//
+// It approximates HeapLive, except in the window where the garbage collector
+// finished marking but did not sweep yet: HeapLive already excludes the dead objects,
+// while they are only added to TotalFree once their spans are swept.
+func (s MemLimitRelatedStats) NetAllocatedBytes() uint64 {
+	if s.TotalFree > s.TotalAlloc {
+		return 0
+	}
+	return s.TotalAlloc - s.TotalFree
+}
+
+// Fragmentation returns the fraction of the in-use heap spans that is not holding allocated objects,
+// 1 - NetAllocatedBytes / HeapInUse, between 0 and 1. It is 0 when HeapInUse is 0.
+//
+// A high value (e.g. above 0.3) means memory pressure is driven by fragmentation rather than by live data:
+// spans are kept by a few small objects each, which is typical after freeing most objects of a long lived set.
+func (s MemLimitRelatedStats) Fragmentation() float64 {
+	if s.HeapInUse == 0 {
+		return 0
+	}
+	allocated := s.NetAllocatedBytes()
+	if allocated >= s.HeapInUse {
+		return 0
+	}
+	return 1 - float64(allocated)/float64(s.HeapInUse)
+}
+
+// Fragmentation returns the fragmentation of the current stats, see MemLimitRelatedStats.Fragmentation.
+func Fragmentation() float64 {
+	return GetMemLimitRelatedStats().Fragmentation()
+}
+
 // return an inconsistent view of the memory limiting state of the application.
 // the values are probed one by one, and a concurrent change to them can rarely
 // cause the number to describe an inconsistent state of the application.
 // It should be used for debugging, troubleshooting,
 // or gaining deep insights into the memory limiting state of the application.
 // To get consistent view (with trade-off of performance), use runtime.ReadMemStats() instead.
-func GetMemLimitRelatedStats() MemLimitRelatedStats {
-	heapGoal := runtimeHeapGoal(&runtimeGCController)
-	return MemLimitRelatedStats{
-		MemoryLimit: uint64(runtimeGCController.memoryLimit.Load()),
-		HeapGoal:    heapGoal,
-		HeapLive:    runtimeGCController.heapLive.Load(),
-		MappedReady: runtimeGCController.mappedReady.Load(),
-		HeapFree:    runtimeGCController.heapFree.load(),
-		TotalAlloc:  runtimeGCController.totalAlloc.Load(),
-		TotalFree:   runtimeGCController.totalFree.Load(),
+//
+// If reading the runtime internals panics, zero stats are returned and IsSupported starts returning false.
+func GetMemLimitRelatedStats() (stats MemLimitRelatedStats) {
+	GetMemLimitRelatedStatsInto(&stats)
+	return stats
+}
+
+// GetMemLimitRelatedStatsInto is like GetMemLimitRelatedStats, but fills dst instead of returning a new value.
+// Hot instrumentation loops (e.g. metrics exporters that sample frequently) can reuse the same struct,
+// and it never allocates. dst is zeroed if reading the runtime internals panics.
+func GetMemLimitRelatedStatsInto(dst *MemLimitRelatedStats) {
+	*dst = MemLimitRelatedStats{}
+	defer markDegradedOnPanic()
+
+	heapGoal, ok := readHeapGoal()
+	if !ok {
+		return
+	}
+	// read into a local first, so a panic halfway doesn't leave dst partially filled.
+	stats := MemLimitRelatedStats{
+		MemoryLimit:  uint64(runtimeGCController.memoryLimit.Load()),
+		HeapGoal:     heapGoal,
+		HeapLive:     runtimeGCController.heapLive.Load(),
+		MappedReady:  runtimeGCController.mappedReady.Load(),
+		HeapFree:     runtimeGCController.heapFree.load(),
+		HeapReleased: runtimeGCController.heapReleased.load(),
+		HeapInUse:    runtimeGCController.heapInUse.load(),
+		TotalAlloc:   runtimeGCController.totalAlloc.Load(),
+		TotalFree:    runtimeGCController.totalFree.Load(),
 	}
+	observeOvershoot(stats.HeapGoal, stats.HeapLive)
+	*dst = stats
 }
@@ -0,0 +1,219 @@
+package rtml
+
+import (
+	"math"
+	"runtime/debug"
+	"testing"
+)
+
+func TestMemLimitReason(t *testing.T) {
+	// a warmed up state with 10 bytes of heap free, to be tweaked by each case.
+	base := memLimitInputs{
+		memoryLimit:    100,
+		effectiveLimit: 100,
+		mappedReady:    110,
+		heapFree:       0,
+		heapGoal:       100,
+		heapLive:       90,
+		warmedUp:       true,
+	}
+
+	tests := []struct {
+		name         string
+		in           func(in *memLimitInputs)
+		conservative bool
+		wantReason   string
+		wantReached  bool
+	}{
+		{
+			name: "no limit",
+			in: func(in *memLimitInputs) {
+				in.memoryLimit, in.effectiveLimit = noMemoryLimit, noMemoryLimit
+				in.mappedReady = noMemoryLimit + 1
+			},
+			wantReason: ReasonBelowMapped,
+		},
+		{
+			name:       "mapped ready below the limit",
+			in:         func(in *memLimitInputs) { in.mappedReady = 99 },
+			wantReason: ReasonBelowMapped,
+		},
+		{
+			name:       "heap free larger than mapped ready",
+			in:         func(in *memLimitInputs) { in.heapFree = 120 },
+			wantReason: ReasonInconsistent,
+		},
+		{
+			name:         "heap free larger than mapped ready, conservative",
+			in:           func(in *memLimitInputs) { in.heapFree = 120 },
+			conservative: true,
+			wantReason:   ReasonInconsistent,
+			wantReached:  true,
+		},
+		{
+			name:       "below the limit after deducting heap free",
+			in:         func(in *memLimitInputs) { in.heapFree = 20 },
+			wantReason: ReasonBelowAfterFree,
+		},
+		{
+			name: "within the headroom",
+			in: func(in *memLimitInputs) {
+				in.effectiveLimit = 90
+				in.heapFree = 15
+			},
+			wantReason:  ReasonHeadroom,
+			wantReached: true,
+		},
+		{
+			name: "above the limit with a headroom, below the goal",
+			in: func(in *memLimitInputs) {
+				in.effectiveLimit = 90
+				in.heapGoal = 200
+			},
+			wantReason:  ReasonHeadroom,
+			wantReached: true,
+		},
+		{
+			name:       "warming up",
+			in:         func(in *memLimitInputs) { in.warmedUp = false },
+			wantReason: ReasonWarmingUp,
+		},
+		{
+			name:         "warming up, conservative",
+			in:           func(in *memLimitInputs) { in.warmedUp = false },
+			conservative: true,
+			wantReason:   ReasonWarmingUp,
+		},
+		{
+			name:       "zero heap live",
+			in:         func(in *memLimitInputs) { in.heapLive = 0 },
+			wantReason: ReasonInconsistent,
+		},
+		{
+			name:         "zero heap live, conservative",
+			in:           func(in *memLimitInputs) { in.heapLive = 0 },
+			conservative: true,
+			wantReason:   ReasonInconsistent,
+			wantReached:  true,
+		},
+		{
+			name:        "zero heap goal",
+			in:          func(in *memLimitInputs) { in.heapGoal = 0 },
+			wantReason:  ReasonGoalUnavailable,
+			wantReached: true,
+		},
+		{
+			name:       "below the goal",
+			in:         func(in *memLimitInputs) {},
+			wantReason: ReasonBelowGoal,
+		},
+		{
+			name:        "above the goal",
+			in:          func(in *memLimitInputs) { in.heapLive = 100 },
+			wantReason:  ReasonAboveGoal,
+			wantReached: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConservativeForTest(t, tt.conservative)
+
+			in := base
+			tt.in(&in)
+			reason := memLimitReason(in)
+			if reason != tt.wantReason {
+				t.Errorf("memLimitReason() = %q, want %q", reason, tt.wantReason)
+			}
+			if reached := reachedForReason(reason); reached != tt.wantReached {
+				t.Errorf("reachedForReason(%q) = %t, want %t", reason, reached, tt.wantReached)
+			}
+		})
+	}
+}
+
+// sets SetConservative for the duration of the test.
+func setConservativeForTest(t *testing.T, enabled bool) {
+	t.Helper()
+	previous := conservative.Load()
+	SetConservative(enabled)
+	t.Cleanup(func() { SetConservative(previous) })
+}
+
+func TestMemLimitReachedForIgnoresProcessWarmUp(t *testing.T) {
+	// the process warmed up long ago, the snapshot alone decides.
+	observeControllerWarmUp(1, 1)
+
+	stats := MemLimitRelatedStats{MemoryLimit: 100, HeapLive: 80, MappedReady: 120}
+	if MemLimitReachedFor(stats) {
+		t.Errorf("MemLimitReachedFor() = true for a snapshot with a zero HeapGoal, want false (warming up)")
+	}
+	stats.HeapGoal = 70
+	if !MemLimitReachedFor(stats) {
+		t.Errorf("MemLimitReachedFor() = false for a snapshot above the limit and the goal, want true")
+	}
+}
+
+func TestIsMemLimitReachedWithoutLimit(t *testing.T) {
+	setMemoryLimitForTest(t, math.MaxInt64)
+
+	// enough live memory to be above any limit a sentinel mistaken for a value could be scaled to.
+	live := make([]byte, 64<<20)
+	if IsMemLimitReached() {
+		t.Errorf("IsMemLimitReached() = true without a memory limit")
+	}
+	if reached, reason := MemLimitStatus(); reached || reason != ReasonBelowMapped {
+		t.Errorf("MemLimitStatus() = %t, %q, want false, %q", reached, reason, ReasonBelowMapped)
+	}
+	_ = live[len(live)-1]
+}
+
+// sets the runtime memory limit for the duration of the test.
+// sets the headroom like Configure with WithHeadroomFraction, which can only be called once per process.
+func setHeadroomForTest(t *testing.T, fraction float64) {
+	t.Helper()
+	previous := headroomPPM.Swap(uint64(fraction * 1_000_000))
+	t.Cleanup(func() { headroomPPM.Store(previous) })
+}
+
+func setMemoryLimitForTest(t *testing.T, limit int64) {
+	t.Helper()
+	previous := debug.SetMemoryLimit(limit)
+	t.Cleanup(func() { debug.SetMemoryLimit(previous) })
+}
+
+func TestMemLimitReasonZeroGoalFollowsMappedMemory(t *testing.T) {
+	// a zero heap goal must not make the limit permanently reached, the memory in use decides instead.
+	tests := []struct {
+		mappedReady, heapFree uint64
+		wantReason            string
+	}{
+		{mappedReady: 90, wantReason: ReasonBelowMapped},
+		{mappedReady: 120, heapFree: 30, wantReason: ReasonBelowAfterFree},
+		{mappedReady: 120, heapFree: 10, wantReason: ReasonGoalUnavailable},
+	}
+	for _, tt := range tests {
+		in := memLimitInputs{
+			memoryLimit:    100,
+			effectiveLimit: 100,
+			mappedReady:    tt.mappedReady,
+			heapFree:       tt.heapFree,
+			heapLive:       80,
+			warmedUp:       true,
+		}
+		if reason := memLimitReason(in); reason != tt.wantReason {
+			t.Errorf("memLimitReason(mappedReady=%d, heapFree=%d) = %q, want %q", tt.mappedReady, tt.heapFree, reason, tt.wantReason)
+		}
+	}
+}
+
+func TestMemLimitReachedForAgreesWithLive(t *testing.T) {
+	Warmup()
+	for _, limit := range []int64{math.MaxInt64, int64(GetMemLimitRelatedStats().MappedReady) + 64<<20} {
+		setMemoryLimitForTest(t, limit)
+		stats := GetMemLimitRelatedStats()
+		if reachedFor, reached := MemLimitReachedFor(stats), IsMemLimitReached(); reachedFor != reached {
+			t.Errorf("MemLimitReachedFor(%s) = %t, while IsMemLimitReached() = %t", stats, reachedFor, reached)
+		}
+	}
+}
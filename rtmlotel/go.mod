@@ -0,0 +1,11 @@
+module github.com/odigos-io/go-rtml/rtmlotel
+
+go 1.23.0
+
+require (
+	github.com/odigos-io/go-rtml v0.0.0
+	go.opentelemetry.io/otel v1.37.0
+	go.opentelemetry.io/otel/trace v1.37.0
+)
+
+replace github.com/odigos-io/go-rtml => ../
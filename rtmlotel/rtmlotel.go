@@ -0,0 +1,45 @@
+// Package rtmlotel annotates OpenTelemetry spans with the memory pressure of the process,
+// in its own module so the core package doesn't depend on OpenTelemetry.
+package rtmlotel
+
+import (
+	rtml "github.com/odigos-io/go-rtml"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attribute keys set by AnnotateSpan.
+const (
+	LimitRatioKey    = attribute.Key("rtml.limit_ratio")
+	PressureLevelKey = attribute.Key("rtml.pressure_level")
+)
+
+// AnnotateSpan sets rtml.limit_ratio (see rtml.LimitRatio) and rtml.pressure_level ("normal", "warning"
+// or "critical", see rtml.GetMemoryPressureLevel) on span, so traces carry the memory context of each operation,
+// e.g. to correlate slow spans with high memory pressure during incident analysis:
+//
+//	ctx, span := tracer.Start(ctx, "handle")
+//	defer span.End()
+//	rtmlotel.AnnotateSpan(span)
+//
+// Both attributes are computed from a single stats read, so they are consistent with each other.
+// Spans that are not recording are skipped without reading the stats.
+func AnnotateSpan(span trace.Span) {
+	AnnotateSpanFor(span, rtml.RuntimeStatsSource())
+}
+
+// AnnotateSpanFor is AnnotateSpan, with the memory pressure of source instead of the go runtime
+// (nil means the runtime). See rtml.StatsSource.
+func AnnotateSpanFor(span trace.Span, source rtml.StatsSource) {
+	if !span.IsRecording() {
+		return
+	}
+	if source == nil {
+		source = rtml.RuntimeStatsSource()
+	}
+	stats := source.Stats()
+	span.SetAttributes(
+		LimitRatioKey.Float64(stats.LimitRatio()),
+		PressureLevelKey.String(rtml.PressureLevelFor(stats).String()),
+	)
+}
@@ -0,0 +1,161 @@
+// Package rtmlprometheus exports go-rtml observations as prometheus metrics.
+// It lives in its own module, so applications that don't use prometheus don't depend on it.
+package rtmlprometheus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// name of the histogram installed by InstallLimitRatioHistogram.
+const limitRatioHistogramName = "rtml_limit_ratio"
+
+// DefaultLimitRatioBuckets are the histogram buckets used unless WithBuckets is passed.
+// They are denser near 1, where the distinction between running close to the edge and crossing it matters.
+var DefaultLimitRatioBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95, 1, 1.05, 1.1, 1.25}
+
+// Option customizes InstallLimitRatioHistogram.
+type Option func(*config)
+
+type config struct {
+	interval time.Duration
+	buckets  []float64
+	source   rtml.StatsSource
+}
+
+// WithSampleInterval sets how often LimitRatio is observed. The default is 1 second.
+func WithSampleInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.interval = interval
+	}
+}
+
+// WithBuckets sets the upper bounds of the histogram buckets, in increasing order.
+// The default is DefaultLimitRatioBuckets.
+func WithBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.buckets = append([]float64(nil), buckets...)
+	}
+}
+
+// WithStatsSource observes the LimitRatio of source instead of the go runtime, see rtml.StatsSource.
+func WithStatsSource(source rtml.StatsSource) Option {
+	return func(c *config) {
+		c.source = source
+	}
+}
+
+// InstallLimitRatioHistogram registers the rtml_limit_ratio histogram with registerer,
+// and starts a background sampler that observes rtml.LimitRatio into it until ctx is done.
+//
+// Unlike a gauge of the current value, the histogram shows the distribution of how close to the limit
+// the service runs, e.g. whether it is chronically near the edge or only occasionally spikes:
+//
+//	err := rtmlprometheus.InstallLimitRatioHistogram(ctx, prometheus.DefaultRegisterer,
+//		rtmlprometheus.WithSampleInterval(5*time.Second))
+//
+// An error is returned, and nothing is started, if the interval is not positive or the registration fails.
+func InstallLimitRatioHistogram(ctx context.Context, registerer prometheus.Registerer, opts ...Option) error {
+	c := config{
+		interval: time.Second,
+		buckets:  DefaultLimitRatioBuckets,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.interval <= 0 {
+		return errors.New("rtmlprometheus: sample interval must be positive")
+	}
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    limitRatioHistogramName,
+		Help:    "Sampled ratio of the memory in use to the memory limit, (MappedReady - HeapFree) / MemoryLimit.",
+		Buckets: c.buckets,
+	})
+	if err := registerer.Register(histogram); err != nil {
+		return err
+	}
+
+	limitRatio := rtml.LimitRatio
+	if c.source != nil {
+		limitRatio = func() float64 {
+			stats := c.source.Stats()
+			return stats.LimitRatio()
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			histogram.Observe(limitRatio())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// statsCollector reports a snapshot of the stats of source on every scrape.
+type statsCollector struct {
+	source rtml.StatsSource
+	fields []rtml.StatField
+	descs  []*prometheus.Desc
+}
+
+// NewStatsCollector returns a collector that reports every rtml.MemLimitRelatedStats field on each scrape,
+// generated from rtml.FieldMetadata: monotonic fields (total_alloc, total_free) as counters named
+// rtml_<field>_<unit>_total, and the others as gauges named rtml_<field>_<unit>, e.g. rtml_heap_live_bytes.
+// rtml_memory_limit_bytes is not reported when no limit is set, instead of the math.MaxInt64 the runtime reports.
+//
+//	prometheus.MustRegister(rtmlprometheus.NewStatsCollector())
+func NewStatsCollector() prometheus.Collector {
+	return NewStatsCollectorFor(rtml.RuntimeStatsSource())
+}
+
+// NewStatsCollectorFor returns a collector like NewStatsCollector, reporting the stats of source
+// instead of the go runtime (nil means the runtime). See rtml.StatsSource.
+func NewStatsCollectorFor(source rtml.StatsSource) prometheus.Collector {
+	if source == nil {
+		source = rtml.RuntimeStatsSource()
+	}
+	fields := rtml.FieldMetadata()
+	descs := make([]*prometheus.Desc, len(fields))
+	for i, field := range fields {
+		name := "rtml_" + field.Name + "_" + field.Unit
+		if field.IsCounter {
+			name += "_total"
+		}
+		descs[i] = prometheus.NewDesc(name, field.Description, nil, nil)
+	}
+	return &statsCollector{source: source, fields: fields, descs: descs}
+}
+
+// Describe implements prometheus.Collector.
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	for i, field := range c.fields {
+		valueType := prometheus.GaugeValue
+		if field.IsCounter {
+			valueType = prometheus.CounterValue
+		}
+		if !field.IsSet(stats) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.descs[i], valueType, float64(field.Value(stats)))
+	}
+}
@@ -0,0 +1,22 @@
+package rtml
+
+import "sync/atomic"
+
+// set once a read of the runtime internals panicked.
+// from then on, the heuristic stops touching the runtime internals it can't trust, and IsSupported returns false.
+var degraded atomic.Bool
+
+// deferred by the functions that read the runtime internals.
+// it swallows a panic and marks the package as degraded, leaving the named results of the caller
+// as they were when the panic happened, so callers set a safe fallback before reading.
+func markDegradedOnPanic() {
+	if r := recover(); r != nil {
+		degraded.Store(true)
+	}
+}
+
+// calls the runtime heapGoal method, and returns false if it panicked.
+func readHeapGoal() (heapGoal uint64, ok bool) {
+	defer markDegradedOnPanic()
+	return runtimeHeapGoal(&runtimeGCController), true
+}
@@ -0,0 +1,212 @@
+package rtml
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// returned by SampleRing.TimeUntilLimit when memory is not growing,
+// so the limit is not expected to be reached at the current rate.
+const NoLimitHorizon = time.Duration(math.MaxInt64)
+
+// EstimateHeadroomBytes estimates how many more bytes the heap can grow
+// before IsMemLimitReached starts returning true.
+//
+// IsMemLimitReached only returns true when the memory in use is above the limit
+// and the live heap is above the heap goal, so the headroom is the larger of the two distances.
+// The limit is the one after the headroom of WithHeadroomFraction and WithOvershootHeadroom: when a headroom
+// is reserved, the limit is reached as soon as the memory in use gets there, so the heap goal is not considered.
+// It returns math.MaxUint64 when no memory limit is set.
+//
+// HeapReleased is not deducted from the memory in use: the runtime already removes released memory
+// from MappedReady when it returns it to the OS, and adds it back once it is reused.
+func EstimateHeadroomBytes() uint64 {
+	return estimateHeadroomBytes(GetMemLimitRelatedStats())
+}
+
+func estimateHeadroomBytes(s MemLimitRelatedStats) uint64 {
+	if s.MemoryLimit == noMemoryLimit {
+		return math.MaxUint64
+	}
+
+	var limitRoom uint64
+	used := uint64(0)
+	if s.MappedReady > s.HeapFree {
+		used = s.MappedReady - s.HeapFree
+	}
+	effectiveLimit := effectiveMemoryLimit(s.MemoryLimit)
+	if used < effectiveLimit {
+		limitRoom = effectiveLimit - used
+	}
+	if effectiveLimit < s.MemoryLimit {
+		// past the headroom the limit is reached whatever the heap goal is, see memLimitReason.
+		return limitRoom
+	}
+
+	var goalRoom uint64
+	if s.HeapLive < s.HeapGoal {
+		goalRoom = s.HeapGoal - s.HeapLive
+	}
+
+	return max(limitRoom, goalRoom)
+}
+
+// a single stats snapshot and the time it was taken.
+type timedSample struct {
+	at    time.Time
+	stats MemLimitRelatedStats
+}
+
+// SampleRing keeps the last N stats snapshots, to compute trends such as
+// the heap growth rate and the time left until the memory limit is reached.
+//
+// Call Sample periodically (e.g. from a ticker), the trend functions extrapolate
+// from the oldest to the newest snapshot in the ring.
+// It is safe for concurrent use.
+type SampleRing struct {
+	source  StatsSource
+	mu      sync.Mutex
+	samples []timedSample
+	next    int
+	full    bool
+}
+
+// NewSampleRing creates a ring that holds up to size snapshots (at least 2).
+func NewSampleRing(size int) *SampleRing {
+	return NewSampleRingFor(size, runtimeStatsSource{})
+}
+
+// NewSampleRingFor creates a ring whose Sample reads source instead of the go runtime. See StatsSource.
+func NewSampleRingFor(size int, source StatsSource) *SampleRing {
+	return &SampleRing{source: sourceOrRuntime(source), samples: make([]timedSample, max(size, 2))}
+}
+
+// Sample reads the current stats and records them in the ring.
+func (r *SampleRing) Sample() MemLimitRelatedStats {
+	stats := r.source.Stats()
+	r.Add(time.Now(), stats)
+	return stats
+}
+
+// Add records a snapshot taken at the given time, replacing the oldest one when the ring is full.
+func (r *SampleRing) Add(at time.Time, stats MemLimitRelatedStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.samples[r.next] = timedSample{at: at, stats: stats}
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// returns the oldest and newest snapshots, and false if there are less than 2.
+func (r *SampleRing) bounds() (oldest, newest timedSample, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.samples)
+	}
+	if count < 2 {
+		return timedSample{}, timedSample{}, false
+	}
+
+	newest = r.samples[(r.next-1+len(r.samples))%len(r.samples)]
+	if r.full {
+		oldest = r.samples[r.next]
+	} else {
+		oldest = r.samples[0]
+	}
+	return oldest, newest, true
+}
+
+// GrowthRate returns the average growth of the live heap in bytes per second
+// across the snapshots in the ring. It is negative when the heap shrinks,
+// and 0 when there are not enough snapshots.
+func (r *SampleRing) GrowthRate() float64 {
+	oldest, newest, ok := r.bounds()
+	if !ok {
+		return 0
+	}
+	elapsed := newest.at.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return (float64(newest.stats.HeapLive) - float64(oldest.stats.HeapLive)) / elapsed
+}
+
+// TimeUntilLimit extrapolates how long it will take, at the current growth rate,
+// until the headroom of the newest snapshot (see EstimateHeadroomBytes) is used up.
+//
+// It gives capacity planners an early warning horizon rather than a reactive trip.
+// NoLimitHorizon is returned when the heap is not growing, when there are not enough
+// snapshots, or when no memory limit is set. 0 means the limit is already reached.
+func (r *SampleRing) TimeUntilLimit() time.Duration {
+	_, newest, ok := r.bounds()
+	if !ok {
+		return NoLimitHorizon
+	}
+
+	rate := r.GrowthRate()
+	if rate <= 0 {
+		return NoLimitHorizon
+	}
+
+	headroom := estimateHeadroomBytes(newest.stats)
+	if headroom == math.MaxUint64 {
+		return NoLimitHorizon
+	}
+
+	seconds := float64(headroom) / rate
+	if seconds >= NoLimitHorizon.Seconds() {
+		return NoLimitHorizon
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// TimeUntilRecovery extrapolates how long it will take, at the current shrink rate of the live heap,
+// until IsMemLimitReached stops returning true for the newest snapshot, i.e. until the memory in use
+// goes back below the limit (after the headroom of WithHeadroomFraction and WithOvershootHeadroom),
+// or the live heap goes back below the heap goal when it decides, whichever comes first.
+//
+// It returns 0 and true when the newest snapshot is not at the limit (or no limit is set),
+// and false when there is no trend to extrapolate from: less than 2 snapshots, a heap that is not shrinking,
+// or a snapshot reported reached only because its values are inconsistent (see SetConservative).
+func (r *SampleRing) TimeUntilRecovery() (time.Duration, bool) {
+	_, newest, ok := r.bounds()
+	if !ok {
+		return 0, false
+	}
+
+	s := newest.stats
+	effectiveLimit := effectiveMemoryLimit(s.MemoryLimit)
+	reason := memLimitReason(snapshotInputs(s, effectiveLimit))
+	if !reachedForReason(reason) {
+		return 0, true
+	}
+
+	// reached, so the memory in use is at or above the effective limit.
+	excess := s.MappedReady - s.HeapFree - effectiveLimit
+	switch reason {
+	case ReasonAboveGoal:
+		excess = min(excess, s.HeapLive-s.HeapGoal)
+	case ReasonHeadroom, ReasonGoalUnavailable:
+		// the memory in use decides alone.
+	default:
+		return 0, false
+	}
+
+	rate := -r.GrowthRate()
+	if rate <= 0 {
+		return 0, false
+	}
+
+	seconds := float64(excess) / rate
+	if seconds >= NoLimitHorizon.Seconds() {
+		return NoLimitHorizon, true
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -0,0 +1,142 @@
+package rtml
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateHeadroomBytes(t *testing.T) {
+	const limit = 1_000_000_000
+	tests := []struct {
+		name     string
+		stats    MemLimitRelatedStats
+		headroom float64
+		want     uint64
+	}{
+		{
+			name:  "no limit",
+			stats: MemLimitRelatedStats{MemoryLimit: math.MaxInt64, MappedReady: 100},
+			want:  math.MaxUint64,
+		},
+		{
+			name:  "limit room larger than goal room",
+			stats: MemLimitRelatedStats{MemoryLimit: limit, MappedReady: 700_000_000, HeapFree: 100_000_000, HeapGoal: 500_000_000, HeapLive: 450_000_000},
+			want:  400_000_000,
+		},
+		{
+			name:  "above the limit, below the goal",
+			stats: MemLimitRelatedStats{MemoryLimit: limit, MappedReady: 1_100_000_000, HeapGoal: 900_000_000, HeapLive: 800_000_000},
+			want:  100_000_000,
+		},
+		{
+			name:     "headroom deducted from the limit room",
+			stats:    MemLimitRelatedStats{MemoryLimit: limit, MappedReady: 700_000_000, HeapFree: 100_000_000, HeapGoal: 500_000_000, HeapLive: 450_000_000},
+			headroom: 0.1,
+			want:     300_000_000,
+		},
+		{
+			name:     "within the headroom, below the goal",
+			stats:    MemLimitRelatedStats{MemoryLimit: limit, MappedReady: 950_000_000, HeapGoal: 900_000_000, HeapLive: 500_000_000},
+			headroom: 0.1,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHeadroomForTest(t, tt.headroom)
+			if got := estimateHeadroomBytes(tt.stats); got != tt.want {
+				t.Errorf("estimateHeadroomBytes(%s) = %d, want %d", tt.stats, got, tt.want)
+			}
+			if tt.want == 0 && !MemLimitReachedFor(tt.stats) {
+				t.Errorf("no room left, but MemLimitReachedFor(%s) = false", tt.stats)
+			}
+		})
+	}
+}
+
+func TestTimeUntilRecoveryWithinHeadroom(t *testing.T) {
+	setHeadroomForTest(t, 0.1)
+
+	// within the headroom and below the goal, so reached for ReasonHeadroom, 50MB above the effective limit,
+	// with the live heap shrinking by 10MB/s.
+	start := time.Now()
+	ring := NewSampleRingFor(2, nil)
+	ring.Add(start, MemLimitRelatedStats{MemoryLimit: 1_000_000_000, MappedReady: 960_000_000, HeapFree: 10_000_000,
+		HeapGoal: 900_000_000, HeapLive: 600_000_000})
+	ring.Add(start.Add(time.Second), MemLimitRelatedStats{MemoryLimit: 1_000_000_000, MappedReady: 950_000_000,
+		HeapGoal: 900_000_000, HeapLive: 590_000_000})
+
+	recovery, ok := ring.TimeUntilRecovery()
+	if !ok || recovery != 5*time.Second {
+		t.Fatalf("TimeUntilRecovery() = %v, %t, want 5s, true", recovery, ok)
+	}
+
+	filter := NewRequestFilter(WithRetryAfter(ring, time.Minute))
+	if retryAfter, ok := filter.RetryAfter(); !ok || retryAfter != 5*time.Second {
+		t.Errorf("RetryAfter() = %v, %t, want the 5s trend", retryAfter, ok)
+	}
+}
+
+func TestTimeUntilLimit(t *testing.T) {
+	start := time.Now()
+	stats := func(heapLive uint64) MemLimitRelatedStats {
+		// 100MB of room below the limit, more than below the goal.
+		return MemLimitRelatedStats{MemoryLimit: 1_000_000_000, MappedReady: 900_000_000, HeapGoal: 560_000_000, HeapLive: heapLive}
+	}
+	tests := []struct {
+		name    string
+		samples []MemLimitRelatedStats
+		want    time.Duration
+	}{
+		{name: "not enough samples", samples: []MemLimitRelatedStats{stats(500_000_000)}, want: NoLimitHorizon},
+		{name: "growing", samples: []MemLimitRelatedStats{stats(500_000_000), stats(505_000_000), stats(520_000_000)}, want: 10 * time.Second},
+		{name: "shrinking", samples: []MemLimitRelatedStats{stats(520_000_000), stats(500_000_000)}, want: NoLimitHorizon},
+		{
+			name: "no limit",
+			samples: []MemLimitRelatedStats{
+				{MemoryLimit: math.MaxInt64, HeapLive: 100},
+				{MemoryLimit: math.MaxInt64, HeapLive: 200},
+			},
+			want: NoLimitHorizon,
+		},
+		{
+			name: "reached",
+			samples: []MemLimitRelatedStats{
+				{MemoryLimit: 1000, MappedReady: 1100, HeapGoal: 800, HeapLive: 850},
+				{MemoryLimit: 1000, MappedReady: 1100, HeapGoal: 800, HeapLive: 900},
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := NewSampleRingFor(3, nil)
+			for i, s := range tt.samples {
+				ring.Add(start.Add(time.Duration(i)*time.Second), s)
+			}
+			if got := ring.TimeUntilLimit(); got != tt.want {
+				t.Errorf("TimeUntilLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleRingKeepsNewest(t *testing.T) {
+	source := &fakeSource{}
+	ring := NewSampleRingFor(2, source)
+	start := time.Now()
+	ring.Add(start, MemLimitRelatedStats{HeapLive: 1_000})
+	ring.Add(start.Add(time.Second), MemLimitRelatedStats{HeapLive: 2_000})
+	ring.Add(start.Add(2*time.Second), MemLimitRelatedStats{HeapLive: 6_000})
+
+	// the oldest snapshot was replaced, the rate is from the last two.
+	if rate := ring.GrowthRate(); rate != 4_000 {
+		t.Errorf("GrowthRate() = %v, want 4000", rate)
+	}
+
+	source.stats = MemLimitRelatedStats{HeapLive: 42}
+	if stats := ring.Sample(); stats != source.stats {
+		t.Errorf("Sample() = %s, want the source stats %s", stats, source.stats)
+	}
+}
@@ -0,0 +1,65 @@
+package rtml
+
+import "sync/atomic"
+
+// ShadowAdmission is a dry run of memory aware admission: it records what IsMemLimitReached
+// would have decided for each unit of work, without rejecting anything.
+//
+// Run it passively in production, compare the would-reject rate with past OOM incidents,
+// and switch to enforcing (calling IsMemLimitReached directly) once the numbers look right.
+// The zero value is ready to use, and it is safe for concurrent use.
+type ShadowAdmission struct {
+	source      StatsSource // nil reads the runtime
+	evaluations atomic.Uint64
+	wouldReject atomic.Uint64
+}
+
+// counters describing what a ShadowAdmission would have rejected.
+type ShadowAdmissionStats struct {
+	// number of times Evaluate was called.
+	Evaluations uint64
+
+	// number of evaluations where the memory limit was reached, and the work would have been rejected.
+	WouldReject uint64
+
+	// WouldReject / Evaluations, or 0 before the first evaluation.
+	WouldRejectRate float64
+}
+
+// NewShadowAdmission creates a shadow admission recorder.
+func NewShadowAdmission() *ShadowAdmission {
+	return &ShadowAdmission{}
+}
+
+// NewShadowAdmissionFor creates a shadow admission recorder that evaluates source instead of the go runtime.
+// See StatsSource.
+func NewShadowAdmissionFor(source StatsSource) *ShadowAdmission {
+	return &ShadowAdmission{source: source}
+}
+
+// Evaluate checks IsMemLimitReached and records the result.
+// It returns whether the work would have been rejected, for logging, but callers must not act on it
+// (that's what IsMemLimitReached is for).
+func (s *ShadowAdmission) Evaluate() bool {
+	reached := sourceOrRuntime(s.source).Reached()
+	s.evaluations.Add(1)
+	if reached {
+		s.wouldReject.Add(1)
+	}
+	return reached
+}
+
+// Stats returns the counts accumulated since the recorder was created.
+func (s *ShadowAdmission) Stats() ShadowAdmissionStats {
+	// read the rejections first, so they are never counted ahead of the evaluations they belong to.
+	wouldReject := s.wouldReject.Load()
+	evaluations := s.evaluations.Load()
+	stats := ShadowAdmissionStats{
+		Evaluations: evaluations,
+		WouldReject: wouldReject,
+	}
+	if evaluations > 0 {
+		stats.WouldRejectRate = float64(wouldReject) / float64(evaluations)
+	}
+	return stats
+}
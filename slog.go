@@ -0,0 +1,56 @@
+package rtml
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LogValue implements slog.LogValuer, so the stats can be attached to
+// structured log lines as a group of attributes.
+func (s MemLimitRelatedStats) LogValue() slog.Value {
+	attrs := make([]slog.Attr, len(statFields))
+	for i, f := range statFields {
+		attrs[i] = slog.Uint64(f.Name, f.value(s))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogPressureTransitions samples the memory pressure level every interval,
+// and writes a log line to logger each time the level changes.
+//
+// The line is logged at Info level for PressureLevelNormal, Warn for PressureLevelWarning,
+// and Error for PressureLevelCritical, with the full stats snapshot attached,
+// so the pressure timeline can be correlated with the rest of the application logs.
+// The initial level is logged once on startup, and the same level is never logged twice in a row.
+//
+// The function blocks until ctx is cancelled, so it is usually invoked in its own goroutine:
+//
+//	go rtml.LogPressureTransitions(ctx, slog.Default(), time.Second)
+func LogPressureTransitions(ctx context.Context, logger *slog.Logger, interval time.Duration) {
+	LogPressureTransitionsFor(ctx, logger, interval, runtimeStatsSource{})
+}
+
+// LogPressureTransitionsFor is LogPressureTransitions, logging the transitions of source instead of the go runtime.
+// See StatsSource.
+func LogPressureTransitionsFor(ctx context.Context, logger *slog.Logger, interval time.Duration, source StatsSource) {
+	monitor := NewMonitor(interval, func(from, to MemoryPressureLevel, stats MemLimitRelatedStats) {
+		logger.LogAttrs(ctx, slogLevelForPressure(to), "memory pressure level changed",
+			slog.String("from", from.String()),
+			slog.String("to", to.String()),
+			slog.Any("stats", stats),
+		)
+	}, WithMonitorStatsSource(source))
+	monitor.Run(ctx)
+}
+
+func slogLevelForPressure(level MemoryPressureLevel) slog.Level {
+	switch level {
+	case PressureLevelWarning:
+		return slog.LevelWarn
+	case PressureLevelCritical:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
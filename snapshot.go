@@ -0,0 +1,110 @@
+package rtml
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// MemorySnapshot combines the cheap rtml stats with selected runtime.MemStats fields,
+// for a one-shot health view, e.g. on a debug endpoint.
+type MemorySnapshot struct {
+	// Cheap: read through the runtime internals (linkname), without stopping the world.
+	Stats MemLimitRelatedStats `json:"stats"`
+
+	// Expensive: the fields below come from runtime.ReadMemStats, which stops the world.
+
+	// The heap size target of the next GC cycle, in bytes.
+	NextGC uint64 `json:"next_gc"`
+
+	// The number of completed GC cycles.
+	NumGC uint32 `json:"num_gc"`
+
+	// The total bytes of memory obtained from the OS.
+	Sys uint64 `json:"sys"`
+
+	// The bytes of physical memory returned to the OS.
+	HeapReleased uint64 `json:"heap_released"`
+}
+
+// FullMemorySnapshot returns the rtml stats together with selected runtime.MemStats fields,
+// for when operators want the fullest picture, and the cost of one ReadMemStats is acceptable.
+//
+// It calls runtime.ReadMemStats, which stops the world for the duration of the call
+// (typically tens of microseconds, more with many goroutines or a large heap).
+// Use it on a debug endpoint or on demand, never per request or in a tight loop:
+// IsMemLimitReached and GetMemLimitRelatedStats are the cheap alternatives.
+//
+// The rtml stats are read first, so the two parts are close in time but not a consistent view.
+func FullMemorySnapshot() MemorySnapshot {
+	snapshot := MemorySnapshot{Stats: GetMemLimitRelatedStats()}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	snapshot.NextGC = memStats.NextGC
+	snapshot.NumGC = memStats.NumGC
+	snapshot.Sys = memStats.Sys
+	snapshot.HeapReleased = memStats.HeapReleased
+	return snapshot
+}
+
+// Snapshot is a stats snapshot tagged with the wall clock time it was taken at,
+// with the LimitRatio and the PressureLevel computed once from the same stats.
+// Logging and metrics code can pass it around instead of reassembling the same bundle,
+// and correlate it with other events by time.
+//
+// The stats are embedded, so their fields and methods are available directly (e.g. snapshot.HeapLive).
+// The promoted MarshalBinary and UnmarshalBinary only encode the stats, not the time, ratio and level.
+type Snapshot struct {
+	MemLimitRelatedStats
+
+	// when the stats were read, from time.Now (with its monotonic clock reading).
+	Time time.Time
+
+	// (MappedReady - HeapFree) / MemoryLimit of the stats, see MemLimitRelatedStats.LimitRatio.
+	LimitRatio float64
+
+	// the pressure level of the stats, see PressureLevelFor.
+	PressureLevel MemoryPressureLevel
+}
+
+// TakeSnapshot reads the stats from the runtime, and tags them with the current time.
+// It is as cheap as GetMemLimitRelatedStats plus time.Now, and never stops the world.
+func TakeSnapshot() Snapshot {
+	stats := GetMemLimitRelatedStats()
+	return SnapshotAt(stats, time.Now())
+}
+
+// SnapshotAt builds a Snapshot from stats read at t, e.g. from a StatsSource or a decoded snapshot
+// that carries its own timestamp. The ratio and the level are computed from stats, the way TakeSnapshot does.
+func SnapshotAt(stats MemLimitRelatedStats, t time.Time) Snapshot {
+	return Snapshot{
+		MemLimitRelatedStats: stats,
+		Time:                 t,
+		LimitRatio:           stats.LimitRatio(),
+		PressureLevel:        PressureLevelFor(stats),
+	}
+}
+
+// String formats the snapshot like the stats, prefixed with the time, ratio and level, e.g.
+//
+//	2024-01-02T15:04:05.000Z LimitRatio=0.52 PressureLevel=normal MemoryLimit=512.00MiB ...
+func (s Snapshot) String() string {
+	return fmt.Sprintf("%s LimitRatio=%.2f PressureLevel=%s %s",
+		s.Time.Format("2006-01-02T15:04:05.000Z07:00"), s.LimitRatio, s.PressureLevel, s.MemLimitRelatedStats)
+}
+
+// LogValue implements slog.LogValuer, adding the time, ratio and level to the attributes of the stats.
+func (s Snapshot) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(statFields)+3)
+	attrs = append(attrs,
+		slog.Time("time", s.Time),
+		slog.Float64("limit_ratio", s.LimitRatio),
+		slog.String("pressure_level", s.PressureLevel.String()),
+	)
+	for _, f := range statFields {
+		attrs = append(attrs, slog.Uint64(f.Name, f.value(s.MemLimitRelatedStats)))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,35 @@
+package rtml
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	before := time.Now()
+	snapshot := TakeSnapshot()
+	after := time.Now()
+
+	if snapshot.Time.Before(before) || snapshot.Time.After(after) {
+		t.Errorf("snapshot time %v is not between %v and %v", snapshot.Time, before, after)
+	}
+	if snapshot.LimitRatio != snapshot.MemLimitRelatedStats.LimitRatio() {
+		t.Errorf("snapshot LimitRatio = %v, want %v", snapshot.LimitRatio, snapshot.MemLimitRelatedStats.LimitRatio())
+	}
+	if level := PressureLevelFor(snapshot.MemLimitRelatedStats); snapshot.PressureLevel != level {
+		t.Errorf("snapshot PressureLevel = %v, want %v", snapshot.PressureLevel, level)
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stats := MemLimitRelatedStats{MemoryLimit: 100, HeapGoal: 100, HeapLive: 95, MappedReady: 110, HeapFree: 5}
+
+	snapshot := SnapshotAt(stats, at)
+	if !snapshot.Time.Equal(at) || snapshot.MemLimitRelatedStats != stats {
+		t.Errorf("SnapshotAt() = %s, want the given stats and time", snapshot)
+	}
+	if snapshot.LimitRatio != 1.05 || snapshot.PressureLevel != PressureLevelWarning {
+		t.Errorf("SnapshotAt() ratio, level = %v, %v, want 1.05, %v", snapshot.LimitRatio, snapshot.PressureLevel, PressureLevelWarning)
+	}
+}
@@ -0,0 +1,77 @@
+package rtml
+
+import "math"
+
+// StatsSource provides the memory limit state to the helpers built on top of the core functions.
+// The helpers that take options accept it with an option (WithStatsSource for Middleware, WithMonitorStatsSource,
+// WithWatchStatsSource), and the others with a "For" variant of their constructor or function
+// (NewAdaptivePoolFor, NewMemGroupFor, NewGOGCControllerFor, ShouldRejectFor, RegisterThresholdFor, ReadinessHandlerFor,
+// DebugHandlerFor, StatsSSEHandlerFor, LogPressureTransitionsFor, NewShadowAdmissionFor, NewSampleRingFor,
+// NewCachedStatsFor, NewStatsEmitterFor). The rtmlprometheus and rtmlotel modules accept one the same way.
+//
+// The helpers read from RuntimeStatsSource by default, and a nil source means the runtime as well.
+// Tests of code built on the helpers can inject a fake with scripted values instead,
+// to exercise the behavior under memory pressure without a real runtime:
+//
+//	type fakeSource struct{ stats rtml.MemLimitRelatedStats }
+//
+//	func (f *fakeSource) Stats() rtml.MemLimitRelatedStats { return f.stats }
+//	func (f *fakeSource) Reached() bool { return rtml.MemLimitReachedFor(f.stats) }
+//
+//	handler := rtml.Middleware(mux, rtml.WithStatsSource(&fakeSource{...}))
+//
+// Implementations must be safe for concurrent use.
+type StatsSource interface {
+	// Stats returns a snapshot of the values, like GetMemLimitRelatedStats.
+	Stats() MemLimitRelatedStats
+
+	// Reached reports whether the memory limit is reached, like IsMemLimitReached.
+	Reached() bool
+}
+
+// RuntimeStatsSource returns the StatsSource backed by the go runtime internals,
+// which reads GetMemLimitRelatedStats and IsMemLimitReached.
+func RuntimeStatsSource() StatsSource {
+	return runtimeStatsSource{}
+}
+
+type runtimeStatsSource struct{}
+
+func (runtimeStatsSource) Stats() MemLimitRelatedStats {
+	return GetMemLimitRelatedStats()
+}
+
+func (runtimeStatsSource) Reached() bool {
+	return IsMemLimitReached()
+}
+
+// the runtime source when source is nil, so the zero values of the helpers read the runtime.
+func sourceOrRuntime(source StatsSource) StatsSource {
+	if source == nil {
+		return runtimeStatsSource{}
+	}
+	return source
+}
+
+// MemUtilizationRatio of source. The runtime source reads only the values it needs, instead of the full stats.
+func utilizationRatioOf(source StatsSource) float64 {
+	if _, ok := source.(runtimeStatsSource); ok {
+		return MemUtilizationRatio()
+	}
+	s := source.Stats()
+	return utilizationRatio(s.MemoryLimit, s.MappedReady, s.HeapFree)
+}
+
+// GetMemoryPressureLevel of source.
+func pressureLevelOf(source StatsSource) MemoryPressureLevel {
+	if _, ok := source.(runtimeStatsSource); ok {
+		return GetMemoryPressureLevel()
+	}
+	if source.Reached() {
+		return PressureLevelCritical
+	}
+	if utilizationRatioOf(source) >= math.Float64frombits(warningRatioBits.Load()) {
+		return PressureLevelWarning
+	}
+	return PressureLevelNormal
+}
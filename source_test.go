@@ -0,0 +1,47 @@
+package rtml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a StatsSource with scripted values.
+type fakeSource struct {
+	stats   MemLimitRelatedStats
+	reached atomic.Bool
+}
+
+func (f *fakeSource) Stats() MemLimitRelatedStats { return f.stats }
+func (f *fakeSource) Reached() bool               { return f.reached.Load() }
+
+func TestHelpersReadInjectedSource(t *testing.T) {
+	source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 100, MappedReady: 100}}
+	source.reached.Store(true)
+
+	if !ShouldRejectFor(source) {
+		t.Error("ShouldRejectFor = false at full utilization")
+	}
+
+	rec := httptest.NewRecorder()
+	ReadinessHandlerFor(PressureLevelCritical, source)(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ReadinessHandlerFor status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	g, _ := NewMemGroupFor(ctx, source)
+	var ran atomic.Bool
+	g.Go(func() error {
+		ran.Store(true)
+		return nil
+	})
+	_ = g.Wait()
+	if ran.Load() {
+		t.Error("MemGroup started a task while the source reports the limit reached")
+	}
+}
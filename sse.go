@@ -0,0 +1,79 @@
+package rtml
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// StatsSSEHandler returns a handler that streams the stats as Server-Sent Events, for a live view without polling.
+// Every interval (1 second when it is not positive) it sends a "stats" event with the MemLimitRelatedStats snapshot encoded as JSON
+// (the same fields as the DebugHandler JSON), and flushes it, until the client disconnects:
+//
+//	http.Handle("/debug/rtml/stream", rtml.StatsSSEHandler(time.Second))
+//
+// and in the browser:
+//
+//	new EventSource("/debug/rtml/stream").addEventListener("stats", e => render(JSON.parse(e.data)))
+//
+// The first snapshot is sent right away. The stream ends when the request context is done,
+// e.g. when the client disconnects or the server shuts down.
+// It responds with 500 if the response writer does not support flushing.
+func StatsSSEHandler(interval time.Duration) http.HandlerFunc {
+	return StatsSSEHandlerFor(interval, runtimeStatsSource{})
+}
+
+// StatsSSEHandlerFor is StatsSSEHandler, streaming the stats of source instead of the go runtime. See StatsSource.
+func StatsSSEHandlerFor(interval time.Duration, source StatsSource) http.HandlerFunc {
+	interval = pollInterval(interval)
+	source = sourceOrRuntime(source)
+	_, fromRuntime := source.(runtimeStatsSource)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if err := rc.Flush(); err != nil {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var stats MemLimitRelatedStats
+		for {
+			if fromRuntime {
+				GetMemLimitRelatedStatsInto(&stats)
+			} else {
+				stats = source.Stats()
+			}
+			data, err := json.Marshal(stats)
+			if err != nil {
+				return
+			}
+			if _, err := w.Write([]byte("event: stats\ndata: ")); err != nil {
+				return
+			}
+			if _, err := w.Write(append(data, '\n', '\n')); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
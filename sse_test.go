@@ -0,0 +1,22 @@
+package rtml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatsSSEHandlerNonPositiveInterval(t *testing.T) {
+	source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 500}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the first snapshot is sent before the context is checked, and the handler must not panic.
+	rec := httptest.NewRecorder()
+	StatsSSEHandlerFor(0, source)(rec, httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx))
+	if body := rec.Body.String(); !strings.HasPrefix(body, "event: stats\ndata: {") {
+		t.Errorf("body = %q, want a stats event", body)
+	}
+}
@@ -0,0 +1,13 @@
+package rtml
+
+// StackScanStats returns the stack scan work the garbage collector accounted for:
+// last is the amount of stack scanned in the last completed cycle,
+// and max is the stack space currently allocated by all goroutines, which bounds the stack
+// the next cycle can scan.
+//
+// The runtime adds the scanned stack to the heap goal (with GOGC), so in goroutine heavy services
+// they help to tell heap pressure apart from stack scan cost when the heap goal behaves unexpectedly.
+// They are scan work estimates in bytes, not a precise measure of the memory used by stacks.
+func StackScanStats() (last, max uint64) {
+	return runtimeGCController.lastStackScan.Load(), runtimeGCController.maxStackScan.Load()
+}
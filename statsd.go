@@ -0,0 +1,116 @@
+package rtml
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GaugeClient is the subset of a statsd client (e.g. the Datadog client) used by StatsEmitter.
+// Clients with a different signature can be adapted with a small wrapper, for example for the Datadog client:
+//
+//	type datadogGauges struct{ client *statsd.Client }
+//
+//	func (d datadogGauges) Gauge(name string, value float64, tags []string) {
+//		_ = d.client.Gauge(name, value, tags, 1)
+//	}
+type GaugeClient interface {
+	Gauge(name string, value float64, tags []string)
+}
+
+// prefix of the gauge names reported by StatsEmitter.
+const statsGaugePrefix = "rtml."
+
+// StatsEmitter periodically reports the MemLimitRelatedStats fields and the utilization ratio
+// as gauges to a statsd client, so the memory limit state can be graphed next to other service metrics.
+//
+// The gauges are rtml.memory_limit, rtml.heap_goal, rtml.heap_live, rtml.mapped_ready,
+// rtml.heap_free, rtml.heap_released, rtml.heap_in_use, rtml.total_alloc, rtml.total_free (all in bytes) and rtml.utilization_ratio.
+// The memory limit is not reported when no limit is set.
+type StatsEmitter struct {
+	source   StatsSource
+	client   GaugeClient
+	interval time.Duration
+	tags     []string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewStatsEmitter creates an emitter that reports the stats to client every interval (1 second when it is not positive),
+// with tags (e.g. "service:ingest") attached to every gauge.
+// The emitter is not running until Start or Run is called.
+func NewStatsEmitter(client GaugeClient, interval time.Duration, tags ...string) *StatsEmitter {
+	return NewStatsEmitterFor(runtimeStatsSource{}, client, interval, tags...)
+}
+
+// NewStatsEmitterFor creates an emitter that reports the stats of source instead of the go runtime.
+// See StatsSource.
+func NewStatsEmitterFor(source StatsSource, client GaugeClient, interval time.Duration, tags ...string) *StatsEmitter {
+	return &StatsEmitter{
+		source:   sourceOrRuntime(source),
+		client:   client,
+		interval: pollInterval(interval),
+		tags:     append([]string(nil), tags...),
+	}
+}
+
+// Run reports the stats until ctx is cancelled.
+// It blocks, so it is usually invoked in its own goroutine.
+func (e *StatsEmitter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.emit(e.source.Stats())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *StatsEmitter) emit(s MemLimitRelatedStats) {
+	for _, f := range statFields {
+		if !f.IsSet(s) {
+			continue
+		}
+		e.client.Gauge(statsGaugePrefix+f.Name, float64(f.value(s)), e.tags)
+	}
+	e.client.Gauge(statsGaugePrefix+"utilization_ratio", utilizationRatio(s.MemoryLimit, s.MappedReady, s.HeapFree), e.tags)
+}
+
+// Start runs the emitter in a background goroutine until Stop is called.
+// Calling Start on a running emitter is a no-op.
+func (e *StatsEmitter) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		e.Run(ctx)
+	}(e.done)
+}
+
+// Stop terminates an emitter started with Start and waits for the loop to exit.
+func (e *StatsEmitter) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel == nil {
+		return
+	}
+
+	e.cancel()
+	<-e.done
+	e.cancel = nil
+	e.done = nil
+}
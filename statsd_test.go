@@ -0,0 +1,53 @@
+package rtml
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+)
+
+// a GaugeClient recording the last value of every gauge.
+type fakeGauges struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (g *fakeGauges) Gauge(name string, value float64, tags []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.values == nil {
+		g.values = make(map[string]float64)
+	}
+	g.values[name] = value
+}
+
+func TestNewStatsEmitterNonPositiveInterval(t *testing.T) {
+	e := NewStatsEmitterFor(&fakeSource{}, &fakeGauges{}, -1)
+	if e.interval != defaultPollInterval {
+		t.Errorf("interval = %v, want %v", e.interval, defaultPollInterval)
+	}
+
+	// must not panic.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e.Run(ctx)
+}
+
+func TestStatsEmitterSkipsUnsetLimit(t *testing.T) {
+	gauges := &fakeGauges{}
+	e := NewStatsEmitterFor(&fakeSource{}, gauges, 0)
+
+	e.emit(MemLimitRelatedStats{MemoryLimit: math.MaxInt64, HeapLive: 10})
+	if _, ok := gauges.values["rtml.memory_limit"]; ok {
+		t.Error("rtml.memory_limit reported without a memory limit")
+	}
+	if gauges.values["rtml.heap_live"] != 10 {
+		t.Errorf("rtml.heap_live = %v, want 10", gauges.values["rtml.heap_live"])
+	}
+
+	e.emit(MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 500})
+	if gauges.values["rtml.memory_limit"] != 1000 || gauges.values["rtml.utilization_ratio"] != 0.5 {
+		t.Errorf("gauges = %v, want a 1000 memory limit and a 0.5 utilization ratio", gauges.values)
+	}
+}
@@ -3,6 +3,12 @@
 # Get Go version from command line argument, default to 1.24
 GO_VERSION=${1:-1.24}
 
+# Get the image tag from command line argument, default to go-rtml-test:latest
+IMAGE_TAG=${2:-go-rtml-test:latest}
+
+# Use podman (or another docker compatible CLI) with CONTAINER_CLI=podman
+CONTAINER_CLI=${CONTAINER_CLI:-docker}
+
 # Build Docker image from parent directory context
 cd ..
-docker build --build-arg GO_VERSION=${GO_VERSION} -f testframework/Dockerfile -t go-rtml-test:latest .
+${CONTAINER_CLI} build --build-arg GO_VERSION=${GO_VERSION} -f testframework/Dockerfile -t ${IMAGE_TAG} .
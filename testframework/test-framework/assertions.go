@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The runner prints its final stats as "RTML_STAT Field=value Field=value ..."
+const statLinePrefix = "RTML_STAT "
+
+// StatAssertion checks a field of the RTML_STAT line printed by the runner.
+// Min and Max are inclusive and accept the memory limit units (e.g. "45M", "1.5Gi"),
+// Equals compares the raw value (e.g. "false" for IsMemLimitReached).
+type StatAssertion struct {
+	Field  string `json:"field"`
+	Min    string `json:"min,omitempty"`
+	Max    string `json:"max,omitempty"`
+	Equals string `json:"equals,omitempty"`
+}
+
+// parseStatLine returns the fields of the last RTML_STAT line in the logs
+func parseStatLine(logs string) map[string]string {
+	var stats map[string]string
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, statLinePrefix)
+		if i < 0 {
+			continue
+		}
+		stats = make(map[string]string)
+		for _, field := range strings.Fields(line[i+len(statLinePrefix):]) {
+			if name, value, found := strings.Cut(field, "="); found {
+				stats[name] = value
+			}
+		}
+	}
+	return stats
+}
+
+// evaluateAssertions checks every assertion against the reported stats,
+// and fills the failure details of the first one that fails
+func (tr *TestRunner) evaluateAssertions(result *TestResult, assertions []StatAssertion) bool {
+	if len(assertions) == 0 {
+		return true
+	}
+
+	stats := parseStatLine(result.Logs)
+	if stats == nil {
+		result.Error = "runner did not report RTML_STAT"
+		result.FailureDetails.Reason = "Missing stats"
+		result.FailureDetails.ExpectedValue = "an RTML_STAT line in the container logs"
+		return false
+	}
+
+	for _, assertion := range assertions {
+		if err := tr.checkAssertion(assertion, stats); err != nil {
+			actual, reported := stats[assertion.Field]
+			if !reported {
+				actual = "not reported"
+			}
+			result.Error = fmt.Sprintf("assertion on %s failed: %v", assertion.Field, err)
+			result.FailureDetails.Reason = fmt.Sprintf("Assertion failed: %s", assertion.Field)
+			result.FailureDetails.ExpectedValue = assertion.expected()
+			result.FailureDetails.ActualValue = actual
+			return false
+		}
+	}
+	return true
+}
+
+func (tr *TestRunner) checkAssertion(assertion StatAssertion, stats map[string]string) error {
+	raw, reported := stats[assertion.Field]
+	if !reported {
+		return fmt.Errorf("field %s is not reported by the runner", assertion.Field)
+	}
+
+	if assertion.Equals != "" && raw != assertion.Equals {
+		return fmt.Errorf("expected %s, got %s", assertion.Equals, raw)
+	}
+	if assertion.Min == "" && assertion.Max == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("value %q is not a number", raw)
+	}
+	if assertion.Min != "" {
+		minValue, err := tr.parseMemoryLimit(assertion.Min)
+		if err != nil {
+			return fmt.Errorf("invalid min: %w", err)
+		}
+		if value < minValue {
+			return fmt.Errorf("%d is below the minimum %d", value, minValue)
+		}
+	}
+	if assertion.Max != "" {
+		maxValue, err := tr.parseMemoryLimit(assertion.Max)
+		if err != nil {
+			return fmt.Errorf("invalid max: %w", err)
+		}
+		if value > maxValue {
+			return fmt.Errorf("%d is above the maximum %d", value, maxValue)
+		}
+	}
+	return nil
+}
+
+// expected describes the assertion for the failure details
+func (a StatAssertion) expected() string {
+	var parts []string
+	if a.Equals != "" {
+		parts = append(parts, fmt.Sprintf("= %s", a.Equals))
+	}
+	switch {
+	case a.Min != "" && a.Max != "":
+		parts = append(parts, fmt.Sprintf("%s-%s", a.Min, a.Max))
+	case a.Min != "":
+		parts = append(parts, fmt.Sprintf(">= %s", a.Min))
+	case a.Max != "":
+		parts = append(parts, fmt.Sprintf("<= %s", a.Max))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The benchmark test prints "RTML_BENCH ns_per_op=... iterations=... allocs_per_op=..." for IsMemLimitReached
+const benchLinePrefix = "RTML_BENCH "
+
+// BenchStats is the measured cost of IsMemLimitReached in a benchmark test
+type BenchStats struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	Iterations  uint64  `json:"iterations"`
+	AllocsPerOp uint64  `json:"allocs_per_op"`
+}
+
+// parseBenchLine returns the benchmark results reported in the logs, or nil when the test is not a benchmark test
+func parseBenchLine(logs string) *BenchStats {
+	var bench *BenchStats
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, benchLinePrefix)
+		if i < 0 {
+			continue
+		}
+		bench = &BenchStats{}
+		for _, field := range strings.Fields(line[i+len(benchLinePrefix):]) {
+			name, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+			switch name {
+			case "ns_per_op":
+				bench.NsPerOp, _ = strconv.ParseFloat(value, 64)
+			case "iterations":
+				bench.Iterations, _ = strconv.ParseUint(value, 10, 64)
+			case "allocs_per_op":
+				bench.AllocsPerOp, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return bench
+}
+
+// checkOverheadBudget fails the test when IsMemLimitReached costs more than maxNsPerOp,
+// or when the runner did not report it. A zero budget disables the check.
+func checkOverheadBudget(result *TestResult, maxNsPerOp float64) bool {
+	if maxNsPerOp <= 0 {
+		return true
+	}
+
+	expected := fmt.Sprintf("<= %g ns/op", maxNsPerOp)
+	if result.Bench == nil {
+		result.Error = "runner did not report RTML_BENCH"
+		result.FailureDetails.Reason = "Missing benchmark results"
+		result.FailureDetails.ExpectedValue = expected
+		return false
+	}
+	if result.Bench.NsPerOp > maxNsPerOp {
+		result.Error = fmt.Sprintf("IsMemLimitReached took %.2f ns/op, above the %g ns/op budget", result.Bench.NsPerOp, maxNsPerOp)
+		result.FailureDetails.Reason = "Overhead budget exceeded"
+		result.FailureDetails.Field = "ns_per_op"
+		result.FailureDetails.ExpectedValue = expected
+		result.FailureDetails.ActualValue = fmt.Sprintf("%.2f ns/op", result.Bench.NsPerOp)
+		return false
+	}
+	return true
+}
+
+// printBenchResults lists the measured cost of IsMemLimitReached of the benchmark tests
+func printBenchResults(results []TestResult) {
+	header := false
+	for _, result := range results {
+		if result.Bench == nil {
+			continue
+		}
+		if !header {
+			fmt.Printf("\n=== Benchmark Results ===\n")
+			header = true
+		}
+		fmt.Printf("%s: IsMemLimitReached %.2f ns/op, %d allocs/op (%d iterations)\n",
+			result.TestName, result.Bench.NsPerOp, result.Bench.AllocsPerOp, result.Bench.Iterations)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Size of the per-test charts, in pixels
+const (
+	chartWidth  = 480
+	chartHeight = 140
+)
+
+// HTMLReporter writes the results as a single self-contained html page, for sharing a run with humans:
+// a table of the tests, and for tests that reported samples (RTML_SAMPLE), an inline svg chart
+// of HeapLive and MappedReady over time. It uses no javascript, so the page renders anywhere.
+type HTMLReporter struct {
+	Path string
+}
+
+// htmlTest is the view of a TestResult rendered by the template
+type htmlTest struct {
+	TestResult
+	Chart *htmlChart
+}
+
+// htmlChart holds the svg polyline points of the series, already scaled to the chart size
+type htmlChart struct {
+	HeapLive    string
+	MappedReady string
+	MaxLabel    string
+	TimeLabel   string
+}
+
+func (r HTMLReporter) Report(results []TestResult) error {
+	tests := make([]htmlTest, len(results))
+	for i, result := range results {
+		tests[i] = htmlTest{TestResult: result, Chart: newHTMLChart(result.Samples)}
+	}
+
+	var page strings.Builder
+	if err := htmlReportTemplate.Execute(&page, tests); err != nil {
+		return fmt.Errorf("failed to render html report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create html report directory: %w", err)
+	}
+	if err := os.WriteFile(r.Path, []byte(page.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write html report: %w", err)
+	}
+	return nil
+}
+
+// newHTMLChart scales the samples to the chart, or returns nil when there are too few to draw a line
+func newHTMLChart(samples []MemorySample) *htmlChart {
+	if len(samples) < 2 {
+		return nil
+	}
+
+	first, last := samples[0].TimeMs, samples[len(samples)-1].TimeMs
+	var maxBytes uint64
+	for _, sample := range samples {
+		if sample.HeapLive > maxBytes {
+			maxBytes = sample.HeapLive
+		}
+		if sample.MappedReady > maxBytes {
+			maxBytes = sample.MappedReady
+		}
+	}
+	if last == first || maxBytes == 0 {
+		return nil
+	}
+
+	points := func(value func(MemorySample) uint64) string {
+		var b strings.Builder
+		for _, sample := range samples {
+			x := float64(sample.TimeMs-first) / float64(last-first) * chartWidth
+			y := chartHeight - float64(value(sample))/float64(maxBytes)*chartHeight
+			fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+		}
+		return strings.TrimSpace(b.String())
+	}
+
+	return &htmlChart{
+		HeapLive:    points(func(s MemorySample) uint64 { return s.HeapLive }),
+		MappedReady: points(func(s MemorySample) uint64 { return s.MappedReady }),
+		MaxLabel:    fmt.Sprintf("%.1f MB", float64(maxBytes)/1024/1024),
+		TimeLabel:   fmt.Sprintf("%.1fs", float64(last-first)/1000),
+	}
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"chartWidth":  func() int { return chartWidth },
+	"chartHeight": func() int { return chartHeight },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-rtml test report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.passed { color: #1a7f37; }
+.failed, .timeout { color: #cf222e; }
+.heap-live { stroke: #0969da; }
+.mapped-ready { stroke: #bf8700; }
+svg polyline { fill: none; stroke-width: 1.5; }
+svg text { font-size: 10px; }
+</style>
+</head>
+<body>
+<h1>go-rtml test report</h1>
+<table>
+<tr><th>Test</th><th>Go</th><th>Status</th><th>Duration</th><th>Exit Code</th><th>Peak Memory</th><th>Error</th><th>Memory over time</th></tr>
+{{range .}}<tr>
+<td>{{.TestName}}</td>
+<td>{{.GoVersion}}</td>
+<td class="{{.Status}}">{{.Status}}{{if .OOMKilled}} (OOM killed){{end}}</td>
+<td>{{printf "%.2f" .Duration}}s</td>
+<td>{{.ExitCode}}</td>
+<td>{{printf "%.2f" .MemoryStats.PeakMemoryMB}} MB</td>
+<td>{{.Error}}</td>
+<td>{{with .Chart}}<svg width="{{chartWidth}}" height="{{chartHeight}}" viewBox="0 -12 {{chartWidth}} {{chartHeight}}" style="overflow: visible">
+<polyline class="mapped-ready" points="{{.MappedReady}}"/>
+<polyline class="heap-live" points="{{.HeapLive}}"/>
+<text x="0" y="-2">{{.MaxLabel}}</text>
+<text x="{{chartWidth}}" y="{{chartHeight}}" text-anchor="end">{{.TimeLabel}}</text>
+</svg><br><span style="color: #0969da">HeapLive</span> / <span style="color: #bf8700">MappedReady</span>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JUnit XML elements, following the format understood by most CI test dashboards
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+const junitSuiteName = "go-rtml"
+
+// JUnitReporter writes the results as JUnit XML, with one testcase per test.
+// Failed tests are reported as <failure> and timed out tests as <error>.
+type JUnitReporter struct {
+	Path string
+}
+
+func (r JUnitReporter) Report(results []TestResult) error {
+	suite := junitTestSuite{
+		Name:  junitSuiteName,
+		Tests: len(results),
+	}
+	if len(results) > 0 {
+		suite.Timestamp = results[0].StartTime.Format("2006-01-02T15:04:05")
+	}
+
+	for _, result := range results {
+		className := junitSuiteName
+		if result.GoVersion != "" {
+			// Group the test cases of a Go version matrix by version
+			className = fmt.Sprintf("%s.go%s", junitSuiteName, result.GoVersion)
+		}
+
+		testCase := junitTestCase{
+			Name:      result.TestName,
+			ClassName: className,
+			Time:      result.Duration,
+			SystemOut: result.Logs,
+		}
+
+		switch result.Status {
+		case "failed":
+			suite.Failures++
+			testCase.Failure = junitProblemFor(result, result.FailureDetails.Reason)
+		case "timeout":
+			suite.Errors++
+			testCase.Error = junitProblemFor(result, "timeout")
+		}
+
+		suite.Time += result.Duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	report := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal junit report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create junit report directory: %w", err)
+	}
+	if err := os.WriteFile(r.Path, append([]byte(xml.Header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+	return nil
+}
+
+// junitProblemFor uses the error as the message and the failure details and log snippet as the body
+func junitProblemFor(result TestResult, problemType string) *junitProblem {
+	var body strings.Builder
+	if result.FailureDetails.Reason != "" {
+		fmt.Fprintf(&body, "Reason: %s\n", result.FailureDetails.Reason)
+	}
+	if result.FailureDetails.ExpectedValue != "" {
+		fmt.Fprintf(&body, "Expected: %s\n", result.FailureDetails.ExpectedValue)
+	}
+	if result.FailureDetails.ActualValue != "" {
+		fmt.Fprintf(&body, "Actual: %s\n", result.FailureDetails.ActualValue)
+	}
+	if result.OOMKilled {
+		fmt.Fprintf(&body, "OOM killed: true\n")
+	}
+	if result.FailureDetails.LogSnippet != "" {
+		fmt.Fprintf(&body, "\n%s\n", result.FailureDetails.LogSnippet)
+	}
+
+	return &junitProblem{
+		Message: result.Error,
+		Type:    problemType,
+		Body:    body.String(),
+	}
+}
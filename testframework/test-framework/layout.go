@@ -0,0 +1,23 @@
+package main
+
+// Subcommand that runs only the layout verification, e.g. "test-framework layout"
+const layoutSubcommand = "layout"
+
+// layoutTestConfigs returns the configs of the layout subcommand: a single small container that
+// only calls rtml.LayoutVerified, and exits nonzero if the mirror doesn't match the runtime.
+// Combined with GO_VERSIONS it checks every version of the matrix in seconds,
+// and the report has one result per version with its go_version and status.
+func layoutTestConfigs() []TestConfig {
+	return []TestConfig{
+		{
+			Name:             "layout-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "64M",
+			TimeoutSeconds:   30,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE": "layout",
+			},
+		},
+	}
+}
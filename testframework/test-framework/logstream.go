@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Serializes the lines streamed by tests running in parallel
+var streamMu sync.Mutex
+
+// prefixWriter writes complete lines to out, each prefixed with the test name.
+// Partial lines are buffered until their newline arrives (or until flush).
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *prefixWriter) flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	fmt.Fprintf(w.out, "%s %s\n", w.prefix, line)
+}
+
+// streamContainerLogs follows the container output and prints it to stdout while the test runs,
+// so a hung test can be diagnosed before it times out. It stops when the container exits or ctx is done.
+func (tr *TestRunner) streamContainerLogs(ctx context.Context, containerID string, testName string) {
+	logs, err := tr.dockerClient.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		log.Printf("Failed to stream logs of test %s: %v", testName, err)
+		return
+	}
+	defer logs.Close()
+
+	writer := &prefixWriter{out: os.Stdout, prefix: fmt.Sprintf("[%s]", testName)}
+	defer writer.flush()
+
+	// Without a TTY, stdout and stderr are multiplexed in a single stream
+	if _, err := stdcopy.StdCopy(writer, writer, logs); err != nil && ctx.Err() == nil {
+		log.Printf("Log stream of test %s ended: %v", testName, err)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -17,10 +20,32 @@ import (
 )
 
 type TestResult struct {
-	TestName    string    `json:"test_name"`
-	Status      string    `json:"status"` // "passed", "failed", "timeout"
-	Duration    float64   `json:"duration_seconds"`
-	ExitCode    int       `json:"exit_code"`
+	TestName  string  `json:"test_name"`
+	GoVersion string  `json:"go_version,omitempty"`
+	Pattern   string  `json:"alloc_pattern,omitempty"`
+	Status    string  `json:"status"` // "passed", "failed", "timeout"
+	Duration  float64 `json:"duration_seconds"`
+	ExitCode  int     `json:"exit_code"`
+	OOMKilled bool    `json:"oom_killed"`
+
+	// Set when docker failed (container create/start/wait) rather than the test itself
+	InfrastructureFailure bool `json:"infrastructure_failure,omitempty"`
+
+	// Every attempt when the test was retried, the last one is the reported result
+	Attempts []TestAttempt `json:"attempts,omitempty"`
+
+	// Set when the container was OOM killed while the last IsMemLimitReached the runner reported (RTML_REACHED) was false
+	OOMWhileNotReached bool `json:"oom_while_not_reached,omitempty"`
+
+	// How IsMemLimitReached behaved during a soak test (RTML_SOAK)
+	Soak *SoakStats `json:"soak,omitempty"`
+
+	// The measured cost of IsMemLimitReached in a benchmark test (RTML_BENCH)
+	Bench *BenchStats `json:"bench,omitempty"`
+
+	// Memory time series reported by the runner while it allocated (RTML_SAMPLE)
+	Samples []MemorySample `json:"samples,omitempty"`
+
 	StartTime   time.Time `json:"start_time"`
 	EndTime     time.Time `json:"end_time"`
 	Error       string    `json:"error,omitempty"`
@@ -34,9 +59,22 @@ type TestResult struct {
 		ExpectedValue string `json:"expected_value,omitempty"`
 		ActualValue   string `json:"actual_value,omitempty"`
 		LogSnippet    string `json:"log_snippet,omitempty"`
+		OOMKilled     bool   `json:"oom_killed,omitempty"`
+
+		// The stat the failed runner check was about, from its RTML_RESULT line
+		Field string `json:"field,omitempty"`
 	} `json:"failure_details,omitempty"`
 }
 
+// TestAttempt summarizes a single run of a retried test
+type TestAttempt struct {
+	Attempt               int     `json:"attempt"`
+	Status                string  `json:"status"`
+	Duration              float64 `json:"duration_seconds"`
+	Error                 string  `json:"error,omitempty"`
+	InfrastructureFailure bool    `json:"infrastructure_failure,omitempty"`
+}
+
 type TestConfig struct {
 	Name             string            `json:"name"`
 	Image            string            `json:"image"`
@@ -44,47 +82,258 @@ type TestConfig struct {
 	MemoryLimit      string            `json:"memory_limit"`
 	TimeoutSeconds   int               `json:"timeout_seconds"`
 	ExpectedExitCode int               `json:"expected_exit_code"`
+
+	// Command (entrypoint and arguments) to run in the container, defaults to the test runner.
+	// Lets one image host several scenario binaries or flags, selected per test.
+	Command []string `json:"command,omitempty"`
+
+	// Whether the kernel OOM killer is expected to stop the container (exit code 137).
+	// A test passes only when the OOM kill outcome matches, so by default an OOM kill fails it.
+	ExpectOOMKilled bool `json:"expect_oom_killed,omitempty"`
+
+	// Number of CPUs the container may use (e.g. 0.5), 0 means no limit.
+	CPULimit float64 `json:"cpu_limit,omitempty"`
+
+	// Swap available on top of MemoryLimit (e.g. "256M"), or "unlimited".
+	// Empty (the default) disables swap, so memory behavior and OOM kills are deterministic.
+	SwapLimit string `json:"swap_limit,omitempty"`
+
+	// Optional Go version (e.g. "1.24") to build the runner image with.
+	// When set, Image is replaced by the runner image built for that version.
+	GoVersion string `json:"go_version,omitempty"`
+
+	// How many times to re-run the test after an infrastructure failure (docker errors).
+	// Assertion failures and timeouts are never retried, so real regressions are not masked.
+	RetryCount int `json:"retry_count,omitempty"`
+
+	// Fail the test early when the runner claims to be allocating (RTML_SAMPLE allocating=true)
+	// but TotalAlloc did not advance for this many seconds, instead of waiting for TimeoutSeconds.
+	// 0 (the default) disables the watchdog.
+	FreezeTimeoutSeconds int `json:"freeze_timeout_seconds,omitempty"`
+
+	// Assertions on the final stats the runner reports (RTML_STAT),
+	// checked after the exit code matches ExpectedExitCode.
+	Assertions []StatAssertion `json:"assertions,omitempty"`
+
+	// Optional overrides of the bounds asserted by the runner sanity check.
+	// Unset fields keep the runner defaults.
+	SanityThresholds *SanityThresholds `json:"sanity_thresholds,omitempty"`
+
+	// Changes the container memory limit to ResizeMemoryTo (e.g. "768M") ResizeAfterSeconds after it started
+	// (docker update), like a kubernetes in-place pod resize. The swap follows SwapLimit. Empty keeps the limit.
+	ResizeMemoryTo     string `json:"resize_memory_to,omitempty"`
+	ResizeAfterSeconds int    `json:"resize_after_seconds,omitempty"`
+
+	// Overhead budget of IsMemLimitReached in ns/op, checked against the RTML_BENCH line of a benchmark test.
+	// A regression making the hot path slower fails the test. 0 (the default) disables the check.
+	MaxNsPerOp float64 `json:"max_ns_per_op,omitempty"`
+}
+
+// Command run in the container when the config doesn't set one
+var defaultCommand = []string{"/app/test-runner"}
+
+func (config TestConfig) command() []string {
+	if len(config.Command) == 0 {
+		return defaultCommand
+	}
+	return config.Command
+}
+
+// SanityThresholds are passed to the runner as SANITY_* environment variables.
+type SanityThresholds struct {
+	HeapLiveMinPercent       *int `json:"heap_live_min_percent,omitempty"`
+	HeapLiveMaxPercent       *int `json:"heap_live_max_percent,omitempty"`
+	MappedReadyMinOverheadMB *int `json:"mapped_ready_min_overhead_mb,omitempty"`
+	MappedReadyMaxOverheadMB *int `json:"mapped_ready_max_overhead_mb,omitempty"`
+	HeapGoalMaxGrowthMB      *int `json:"heap_goal_max_growth_mb,omitempty"`
+	TotalAllocMinPercent     *int `json:"total_alloc_min_percent,omitempty"`
+	TotalAllocMaxPercent     *int `json:"total_alloc_max_percent,omitempty"`
+	TotalFreeMaxMB           *int `json:"total_free_max_mb,omitempty"`
+}
+
+func (t *SanityThresholds) envVars() map[string]string {
+	env := make(map[string]string)
+	if t == nil {
+		return env
+	}
+	set := func(key string, value *int) {
+		if value != nil {
+			env[key] = fmt.Sprintf("%d", *value)
+		}
+	}
+	set("SANITY_HEAP_LIVE_MIN_PERCENT", t.HeapLiveMinPercent)
+	set("SANITY_HEAP_LIVE_MAX_PERCENT", t.HeapLiveMaxPercent)
+	set("SANITY_MAPPED_READY_MIN_OVERHEAD_MB", t.MappedReadyMinOverheadMB)
+	set("SANITY_MAPPED_READY_MAX_OVERHEAD_MB", t.MappedReadyMaxOverheadMB)
+	set("SANITY_HEAP_GOAL_MAX_GROWTH_MB", t.HeapGoalMaxGrowthMB)
+	set("SANITY_TOTAL_ALLOC_MIN_PERCENT", t.TotalAllocMinPercent)
+	set("SANITY_TOTAL_ALLOC_MAX_PERCENT", t.TotalAllocMaxPercent)
+	set("SANITY_TOTAL_FREE_MAX_MB", t.TotalFreeMaxMB)
+	return env
 }
 
 type TestRunner struct {
 	dockerClient *client.Client
+	runtime      ContainerRuntime
 	results      []TestResult
+
+	// Maximum number of tests (containers) running at the same time
+	concurrency int
+
+	// Print the container output prefixed with the test name while the tests run
+	streamLogs bool
+
+	// How the log snippet of failed tests is extracted
+	logSnippet LogSnippetConfig
+
+	// Maximum sum of container memory limits running at the same time, 0 means no budget.
+	// Keeps parallel runs from starting so many containers that the host itself runs out of memory.
+	memoryBudget int64
+
+	mu          sync.Mutex
+	budgetCond  *sync.Cond
+	budgetInUse int64
 }
 
-func NewTestRunner() (*TestRunner, error) {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func NewTestRunner(runtime ContainerRuntime, concurrency int) (*TestRunner, error) {
+	dockerClient, err := newContainerClient(runtime)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
+		return nil, fmt.Errorf("failed to create %s client: %w", runtime, err)
 	}
 
-	return &TestRunner{
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	tr := &TestRunner{
 		dockerClient: dockerClient,
+		runtime:      runtime,
 		results:      make([]TestResult, 0),
-	}, nil
+		concurrency:  concurrency,
+		logSnippet:   DefaultLogSnippetConfig(),
+	}
+	tr.budgetCond = sync.NewCond(&tr.mu)
+	return tr, nil
+}
+
+// SetStreamLogs enables printing the container logs live while tests run.
+// The full logs are still captured in the results.
+func (tr *TestRunner) SetStreamLogs(stream bool) {
+	tr.streamLogs = stream
+}
+
+// SetMemoryBudget limits the sum of memory limits of containers running in parallel.
+// A test whose memory limit exceeds the whole budget runs when nothing else is running.
+func (tr *TestRunner) SetMemoryBudget(bytes int64) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.memoryBudget = bytes
+}
+
+// acquireMemory blocks until the container memory fits in the budget
+func (tr *TestRunner) acquireMemory(memory int64) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for tr.memoryBudget > 0 && tr.budgetInUse > 0 && tr.budgetInUse+memory > tr.memoryBudget {
+		tr.budgetCond.Wait()
+	}
+	tr.budgetInUse += memory
+}
+
+func (tr *TestRunner) releaseMemory(memory int64) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.budgetInUse -= memory
+	tr.budgetCond.Broadcast()
 }
 
 func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult {
+	var attempts []TestAttempt
+	for attempt := 1; ; attempt++ {
+		result := tr.runTestAttempt(ctx, config)
+		attempts = append(attempts, TestAttempt{
+			Attempt:               attempt,
+			Status:                result.Status,
+			Duration:              result.EndTime.Sub(result.StartTime).Seconds(),
+			Error:                 result.Error,
+			InfrastructureFailure: result.InfrastructureFailure,
+		})
+
+		if !result.InfrastructureFailure || attempt > config.RetryCount || ctx.Err() != nil {
+			if len(attempts) > 1 {
+				result.Attempts = attempts
+			}
+			return result
+		}
+		log.Printf("Test %s hit an infrastructure failure (attempt %d/%d), retrying: %s",
+			config.Name, attempt, config.RetryCount+1, result.Error)
+	}
+}
+
+func (tr *TestRunner) runTestAttempt(ctx context.Context, config TestConfig) TestResult {
 	result := TestResult{
 		TestName:  config.Name,
+		GoVersion: config.GoVersion,
 		StartTime: time.Now(),
 	}
 
 	log.Printf("Starting test: %s", config.Name)
-	log.Printf("Container config: Image=%s, MemoryLimit=%s, Timeout=%ds",
-		config.Image, config.MemoryLimit, config.TimeoutSeconds)
+	log.Printf("Container config: Image=%s, MemoryLimit=%s, SwapLimit=%s, CPULimit=%g, Timeout=%ds",
+		config.Image, config.MemoryLimit, config.SwapLimit, config.CPULimit, config.TimeoutSeconds)
+
+	memoryLimit, err := tr.parseMemoryLimit(config.MemoryLimit)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		result.EndTime = time.Now()
+		result.FailureDetails.Reason = "Invalid test configuration"
+		result.FailureDetails.ActualValue = config.MemoryLimit
+		return result
+	}
+	if memoryLimit == 0 {
+		log.Printf("No memory limit set for test %s, the container is unlimited", config.Name)
+	}
+
+	memorySwap, err := tr.memorySwapFor(memoryLimit, config.SwapLimit)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		result.EndTime = time.Now()
+		result.FailureDetails.Reason = "Invalid test configuration"
+		result.FailureDetails.ActualValue = config.SwapLimit
+		return result
+	}
 
 	// Create container config
 	containerConfig := &container.Config{
 		Image: config.Image,
-		Env:   tr.buildEnvVars(config.EnvVars),
-		Cmd:   []string{"/app/test-runner"},
+		Env:   tr.buildEnvVars(config.EnvVars, config.SanityThresholds.envVars()),
+		Cmd:   config.command(),
 	}
 
 	// Create host config with memory limit
+	var resizeMemory, resizeSwap int64
+	if config.ResizeMemoryTo != "" {
+		resizeMemory, err = tr.parseMemoryLimit(config.ResizeMemoryTo)
+		if err == nil {
+			resizeSwap, err = tr.memorySwapFor(resizeMemory, config.SwapLimit)
+		}
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			result.EndTime = time.Now()
+			result.FailureDetails.Reason = "Invalid test configuration"
+			result.FailureDetails.ActualValue = config.ResizeMemoryTo
+			return result
+		}
+	}
+
 	hostConfig := &container.HostConfig{
 		AutoRemove: false, // Disable auto-remove to prevent race condition
 		Resources: container.Resources{
-			Memory: tr.parseMemoryLimit(config.MemoryLimit),
+			Memory:     memoryLimit,
+			MemorySwap: memorySwap,
+			NanoCPUs:   int64(config.CPULimit * 1e9),
 		},
 	}
 
@@ -95,6 +344,7 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.Error = fmt.Sprintf("failed to create container: %v", err)
 		result.EndTime = time.Now()
 		result.FailureDetails.Reason = "Container creation failed"
+		result.InfrastructureFailure = true
 		result.FailureDetails.ActualValue = err.Error()
 		return result
 	}
@@ -114,6 +364,7 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.Error = fmt.Sprintf("failed to start container: %v", err)
 		result.EndTime = time.Now()
 		result.FailureDetails.Reason = "Container start failed"
+		result.InfrastructureFailure = true
 		result.FailureDetails.ActualValue = err.Error()
 		return result
 	}
@@ -124,6 +375,7 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.Error = fmt.Sprintf("failed to inspect container after start: %v", err)
 		result.EndTime = time.Now()
 		result.FailureDetails.Reason = "Container inspection failed"
+		result.InfrastructureFailure = true
 		result.FailureDetails.ActualValue = err.Error()
 		return result
 	} else if containerInfo.State == nil || !containerInfo.State.Running {
@@ -131,6 +383,7 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.Error = fmt.Sprintf("container is not running after start, state: %+v", containerInfo.State)
 		result.EndTime = time.Now()
 		result.FailureDetails.Reason = "Container not running after start"
+		result.InfrastructureFailure = true
 		result.FailureDetails.ActualValue = fmt.Sprintf("State: %+v", containerInfo.State)
 		return result
 	}
@@ -144,6 +397,21 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 	statsCtx, statsCancel := context.WithCancel(ctx)
 	defer statsCancel()
 
+	if tr.streamLogs {
+		go tr.streamContainerLogs(statsCtx, containerID, config.Name)
+	}
+
+	if config.ResizeMemoryTo != "" {
+		go tr.resizeContainerMemory(statsCtx, containerID, time.Duration(config.ResizeAfterSeconds)*time.Second, resizeMemory, resizeSwap)
+	}
+
+	// Stays nil (never ready) when the watchdog is disabled
+	var frozenCh chan MemorySample
+	if config.FreezeTimeoutSeconds > 0 {
+		frozenCh = make(chan MemorySample, 1)
+		go tr.watchForFreeze(statsCtx, containerID, time.Duration(config.FreezeTimeoutSeconds)*time.Second, frozenCh)
+	}
+
 	var peakMemory uint64
 	var finalMemory uint64
 	var statsCollected bool
@@ -167,21 +435,10 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 			}
 			stats.Body.Close()
 
-			// Use RSS (Resident Set Size) if available, otherwise fall back to Usage
-			var usage uint64
-			if rss, exists := containerStats.MemoryStats.Stats["rss"]; exists && rss > 0 {
-				usage = rss
-			} else if containerStats.MemoryStats.Usage > 0 {
-				usage = containerStats.MemoryStats.Usage
-			} else {
-				// If both are 0, try to get from cache stats
-				if cache, exists := containerStats.MemoryStats.Stats["cache"]; exists {
-					if rss, exists := containerStats.MemoryStats.Stats["rss"]; exists {
-						usage = cache + rss
-					} else {
-						usage = cache
-					}
-				}
+			usage := memoryUsageFromStats(containerStats)
+			if usage == 0 {
+				// The stats API had nothing useful, read the container cgroup directly
+				usage = readContainerCgroupMemory(containerID)
 			}
 
 			if usage > 0 {
@@ -216,6 +473,16 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.EndTime = time.Now()
 		result.Duration = result.EndTime.Sub(result.StartTime).Seconds()
 
+		// The exit code alone doesn't tell whether the kernel OOM killer stopped the container
+		if containerInfo, err := tr.dockerClient.ContainerInspect(ctx, containerID); err == nil {
+			if containerInfo.State != nil && containerInfo.State.OOMKilled {
+				result.OOMKilled = true
+				log.Printf("Container %s was OOM killed", containerID[:12])
+			}
+		} else {
+			log.Printf("Failed to inspect container after exit: %v", err)
+		}
+
 		// Get container logs with better error handling
 		logs, err := tr.dockerClient.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
 		if err == nil {
@@ -231,6 +498,26 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 			result.Logs = fmt.Sprintf("Failed to get logs: %v", err)
 		}
 
+		result.Pattern = parseAllocPattern(result.Logs)
+		result.Soak = parseSoakLine(result.Logs)
+		result.Bench = parseBenchLine(result.Logs)
+		result.Samples = parseSamples(result.Logs)
+		runnerResult, _ := parseResultLine(result.Logs)
+		if reached, found := parseLastReached(result.Logs); found && !reached && result.OOMKilled {
+			result.OOMWhileNotReached = true
+		}
+
+		// Prefer the values the runner measured itself over the docker stats API,
+		// which is flaky and depends on the host cgroup version
+		if rss, found := parseRunnerBytes(result.Logs, "RTML_FINAL_RSS_BYTES"); found {
+			finalMemory = rss
+			statsCollected = true
+		}
+		if rss, found := parseRunnerBytes(result.Logs, "RTML_PEAK_RSS_BYTES"); found && rss > peakMemory {
+			peakMemory = rss
+			statsCollected = true
+		}
+
 		// Set collected memory stats
 		result.MemoryStats.PeakMemoryMB = float64(peakMemory) / (1024 * 1024)
 		result.MemoryStats.FinalMemoryMB = float64(finalMemory) / (1024 * 1024)
@@ -243,14 +530,38 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		}
 
 		// Determine test status with detailed error information
-		if result.ExitCode == config.ExpectedExitCode {
-			result.Status = "passed"
+		if result.ExitCode == config.ExpectedExitCode && result.OOMKilled != config.ExpectOOMKilled {
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("expected OOM killed %t, got %t", config.ExpectOOMKilled, result.OOMKilled)
+			result.FailureDetails.Reason = "Unexpected OOM kill outcome"
+			result.FailureDetails.ExpectedValue = fmt.Sprintf("OOM killed: %t", config.ExpectOOMKilled)
+			result.FailureDetails.ActualValue = fmt.Sprintf("OOM killed: %t", result.OOMKilled)
+			result.FailureDetails.OOMKilled = result.OOMKilled
+			result.FailureDetails.LogSnippet = tr.extractRelevantLogSnippet(result.Logs)
+		} else if result.ExitCode == config.ExpectedExitCode {
+			if tr.evaluateAssertions(&result, config.Assertions) && checkOverheadBudget(&result, config.MaxNsPerOp) {
+				result.Status = "passed"
+			} else {
+				result.Status = "failed"
+				result.FailureDetails.LogSnippet = tr.extractRelevantLogSnippet(result.Logs)
+			}
 		} else {
 			result.Status = "failed"
 			result.Error = fmt.Sprintf("expected exit code %d, got %d", config.ExpectedExitCode, result.ExitCode)
 			result.FailureDetails.Reason = "Unexpected exit code"
 			result.FailureDetails.ExpectedValue = fmt.Sprintf("%d", config.ExpectedExitCode)
 			result.FailureDetails.ActualValue = fmt.Sprintf("%d", result.ExitCode)
+			result.FailureDetails.OOMKilled = result.OOMKilled
+			if result.OOMKilled {
+				result.FailureDetails.Reason = "Container was OOM killed"
+			} else if runnerResult.failed() {
+				// The runner told which check failed, no need to guess it from the logs
+				result.Error = fmt.Sprintf("runner check failed: %s", runnerResult.Reason)
+				result.FailureDetails.Reason = runnerResult.Reason
+				result.FailureDetails.Field = runnerResult.Field
+				result.FailureDetails.ExpectedValue = runnerResult.Expected
+				result.FailureDetails.ActualValue = runnerResult.Actual
+			}
 
 			// Extract relevant log snippet for debugging
 			if result.Logs != "" {
@@ -263,19 +574,43 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 		result.Error = fmt.Sprintf("container wait error: %v", err)
 		result.EndTime = time.Now()
 		result.FailureDetails.Reason = "Container wait failed"
+		result.InfrastructureFailure = true
 		result.FailureDetails.ActualValue = err.Error()
 
 		// Try to get container info to understand what happened
 		if containerInfo, infoErr := tr.dockerClient.ContainerInspect(ctx, containerID); infoErr == nil {
 			log.Printf("Container state: %+v", containerInfo.State)
 			if containerInfo.State != nil {
-				result.FailureDetails.LogSnippet = fmt.Sprintf("Container state: %s, Exit code: %d",
-					containerInfo.State.Status, containerInfo.State.ExitCode)
+				result.OOMKilled = containerInfo.State.OOMKilled
+				result.FailureDetails.OOMKilled = containerInfo.State.OOMKilled
+				result.FailureDetails.LogSnippet = fmt.Sprintf("Container state: %s, Exit code: %d, OOM killed: %t",
+					containerInfo.State.Status, containerInfo.State.ExitCode, containerInfo.State.OOMKilled)
 			}
 		} else {
 			log.Printf("Failed to inspect container: %v", infoErr)
 		}
 
+	case sample := <-frozenCh:
+		result.Status = "failed"
+		result.Error = fmt.Sprintf("runner froze: TotalAlloc stopped advancing for %d seconds while allocating", config.FreezeTimeoutSeconds)
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime).Seconds()
+		result.FailureDetails.Reason = "Runner froze"
+		result.FailureDetails.ExpectedValue = fmt.Sprintf("TotalAlloc advancing within %d seconds", config.FreezeTimeoutSeconds)
+		result.FailureDetails.ActualValue = fmt.Sprintf("last sample: %s", sample)
+		log.Printf("Test %s froze, stopping it early: %s", config.Name, sample)
+
+		logs, err := tr.dockerClient.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+		if err == nil {
+			defer logs.Close()
+			logContent, err := io.ReadAll(logs)
+			if err == nil {
+				result.Logs = string(logContent)
+				result.Samples = parseSamples(result.Logs)
+				result.FailureDetails.LogSnippet = tr.extractRelevantLogSnippet(result.Logs)
+			}
+		}
+
 	case <-waitCtx.Done():
 		result.Status = "timeout"
 		result.Error = "test timed out"
@@ -301,165 +636,202 @@ func (tr *TestRunner) RunTest(ctx context.Context, config TestConfig) TestResult
 	return result
 }
 
-func (tr *TestRunner) buildEnvVars(envVars map[string]string) []string {
-	var env []string
-	for k, v := range envVars {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
+// memoryUsageFromStats extracts the container working set from the docker stats,
+// handling both cgroup v1 ("rss", "cache") and cgroup v2 ("anon", "file", "inactive_file") field names
+func memoryUsageFromStats(containerStats types.StatsJSON) uint64 {
+	memStats := containerStats.MemoryStats.Stats
+
+	// cgroup v1: RSS (Resident Set Size)
+	if rss, exists := memStats["rss"]; exists && rss > 0 {
+		return rss
 	}
-	return env
-}
 
-func (tr *TestRunner) parseMemoryLimit(limit string) int64 {
-	// Simple memory parsing - in production you'd want more robust parsing
-	var multiplier int64 = 1
-	if len(limit) > 0 {
-		switch limit[len(limit)-1] {
-		case 'G', 'g':
-			multiplier = 1024 * 1024 * 1024
-			limit = limit[:len(limit)-1]
-		case 'M', 'm':
-			multiplier = 1024 * 1024
-			limit = limit[:len(limit)-1]
-		case 'K', 'k':
-			multiplier = 1024
-			limit = limit[:len(limit)-1]
+	// cgroup v2: working set is the usage minus the inactive page cache,
+	// which is the same metric kubernetes uses for eviction decisions
+	if containerStats.MemoryStats.Usage > 0 {
+		if inactiveFile, exists := memStats["inactive_file"]; exists && inactiveFile < containerStats.MemoryStats.Usage {
+			return containerStats.MemoryStats.Usage - inactiveFile
 		}
+		return containerStats.MemoryStats.Usage
 	}
 
-	var value int64
-	fmt.Sscanf(limit, "%d", &value)
-	return value * multiplier
-}
+	// cgroup v2 without usage: anonymous memory is the closest to RSS
+	if anon, exists := memStats["anon"]; exists && anon > 0 {
+		return anon
+	}
 
-func (tr *TestRunner) RunTestSuite(ctx context.Context, configs []TestConfig) {
-	for _, config := range configs {
-		result := tr.RunTest(ctx, config)
-		tr.results = append(tr.results, result)
+	// cgroup v1 without usage and rss: page cache only
+	if cache, exists := memStats["cache"]; exists {
+		return cache
 	}
+
+	return 0
 }
 
-func (tr *TestRunner) GenerateReport() {
-	// Create results directory
-	resultsDir := "test-results"
-	if err := os.MkdirAll(resultsDir, 0755); err != nil {
-		log.Printf("Failed to create results directory: %v", err)
-		return
+// readContainerCgroupMemory reads the container memory usage from the host cgroup filesystem.
+// It covers cgroup v2 with the systemd and cgroupfs drivers (and rootful podman), and cgroup v1.
+// Returns 0 when the container cgroup can't be found (e.g. remote docker host).
+func readContainerCgroupMemory(containerID string) uint64 {
+	paths := []string{
+		filepath.Join("/sys/fs/cgroup/system.slice", "docker-"+containerID+".scope", "memory.current"),
+		filepath.Join("/sys/fs/cgroup/docker", containerID, "memory.current"),
+		filepath.Join("/sys/fs/cgroup/machine.slice", "libpod-"+containerID+".scope", "memory.current"),
+		filepath.Join("/sys/fs/cgroup/memory/docker", containerID, "memory.usage_in_bytes"),
 	}
-
-	// Generate JSON report
-	reportPath := filepath.Join(resultsDir, "test-report.json")
-	reportData, err := json.MarshalIndent(tr.results, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal report: %v", err)
-		return
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if usage, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return usage
+		}
 	}
+	return 0
+}
 
-	if err := os.WriteFile(reportPath, reportData, 0644); err != nil {
-		log.Printf("Failed to write report: %v", err)
-		return
+func (tr *TestRunner) buildEnvVars(envVars map[string]string, thresholdEnvVars map[string]string) []string {
+	var env []string
+	for k, v := range thresholdEnvVars {
+		// Explicit env vars in the config take precedence
+		if _, exists := envVars[k]; !exists {
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
 	}
+	for k, v := range envVars {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
 
-	// Generate detailed summary
-	passed := 0
-	failed := 0
-	timeout := 0
+// Memory limit suffixes, longest first so "Mi" is matched before "M".
+// Single letter suffixes are binary, like in docker ("512M" is 512MiB).
+var memoryLimitUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10},
+	{"B", 1}, {"b", 1},
+}
+
+// parseMemoryLimit parses limits like "512M", "1.5Gi" or "100MB" into bytes.
+// An empty or zero limit means no limit, and is returned as 0.
+func (tr *TestRunner) parseMemoryLimit(limit string) (int64, error) {
+	limit = strings.TrimSpace(limit)
+	if limit == "" {
+		return 0, nil
+	}
 
-	for _, result := range tr.results {
-		switch result.Status {
-		case "passed":
-			passed++
-		case "failed":
-			failed++
-		case "timeout":
-			timeout++
+	number, multiplier := limit, float64(1)
+	for _, unit := range memoryLimitUnits {
+		if strings.HasSuffix(limit, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(limit, unit.suffix))
+			multiplier = unit.multiplier
+			break
 		}
 	}
 
-	fmt.Printf("\n=== Test Results Summary ===\n")
-	fmt.Printf("Total Tests: %d\n", len(tr.results))
-	fmt.Printf("Passed: %d\n", passed)
-	fmt.Printf("Failed: %d\n", failed)
-	fmt.Printf("Timeout: %d\n", timeout)
-	fmt.Printf("Report saved to: %s\n", reportPath)
-
-	// Print detailed failure information
-	if failed > 0 || timeout > 0 {
-		fmt.Printf("\n=== Failure Details ===\n")
-		for _, result := range tr.results {
-			if result.Status != "passed" {
-				fmt.Printf("\n❌ Test: %s\n", result.TestName)
-				fmt.Printf("   Status: %s\n", result.Status)
-				fmt.Printf("   Duration: %.2f seconds\n", result.Duration)
-				fmt.Printf("   Exit Code: %d\n", result.ExitCode)
-				fmt.Printf("   Error: %s\n", result.Error)
-
-				if result.FailureDetails.Reason != "" {
-					fmt.Printf("   Reason: %s\n", result.FailureDetails.Reason)
-					if result.FailureDetails.ExpectedValue != "" {
-						fmt.Printf("   Expected: %s\n", result.FailureDetails.ExpectedValue)
-					}
-					if result.FailureDetails.ActualValue != "" {
-						fmt.Printf("   Actual: %s\n", result.FailureDetails.ActualValue)
-					}
-				}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid memory limit %q", limit)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("invalid memory limit %q: must not be negative", limit)
+	}
 
-				if result.FailureDetails.LogSnippet != "" {
-					fmt.Printf("   Log Snippet:\n")
-					lines := strings.Split(result.FailureDetails.LogSnippet, "\n")
-					for _, line := range lines {
-						if strings.TrimSpace(line) != "" {
-							fmt.Printf("     %s\n", line)
-						}
-					}
-				}
+	bytes := value * multiplier
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid memory limit %q: too large", limit)
+	}
+	return int64(bytes), nil
+}
 
-				if result.MemoryStats.PeakMemoryMB > 0 {
-					fmt.Printf("   Peak Memory: %.2f MB\n", result.MemoryStats.PeakMemoryMB)
-				}
-			}
-		}
+// memorySwapFor returns the docker MemorySwap value, which is the total of memory and swap.
+// Setting it equal to the memory limit disables swap.
+func (tr *TestRunner) memorySwapFor(memoryLimit int64, swapLimit string) (int64, error) {
+	if memoryLimit == 0 {
+		// Swap can only be limited together with memory
+		return 0, nil
+	}
+	if swapLimit == "unlimited" {
+		return -1, nil
 	}
 
-	// Print success information
-	if passed > 0 {
-		fmt.Printf("\n=== Success Details ===\n")
-		for _, result := range tr.results {
-			if result.Status == "passed" {
-				fmt.Printf("✅ Test: %s (%.2fs, Peak: %.2f MB)\n",
-					result.TestName, result.Duration, result.MemoryStats.PeakMemoryMB)
-			}
-		}
+	swap, err := tr.parseMemoryLimit(swapLimit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid swap limit: %w", err)
 	}
+	return memoryLimit + swap, nil
 }
 
-// extractRelevantLogSnippet extracts the most relevant part of logs for debugging
-func (tr *TestRunner) extractRelevantLogSnippet(logs string) string {
-	if logs == "" {
-		return ""
+func (tr *TestRunner) RunTestSuite(ctx context.Context, configs []TestConfig) {
+	// Results are stored by config index, so the report order is deterministic
+	// regardless of the order in which parallel tests complete
+	results := make([]TestResult, len(configs))
+	sem := make(chan struct{}, tr.concurrency)
+	var wg sync.WaitGroup
+
+	for i, config := range configs {
+		sem <- struct{}{}
+		// An invalid limit fails in RunTest, and does not take any of the budget
+		memory, _ := tr.parseMemoryLimit(config.MemoryLimit)
+		tr.acquireMemory(memory)
+
+		wg.Add(1)
+		go func(i int, config TestConfig) {
+			defer func() {
+				tr.releaseMemory(memory)
+				<-sem
+				wg.Done()
+			}()
+			results[i] = tr.RunTest(ctx, config)
+		}(i, config)
 	}
+	wg.Wait()
 
-	lines := strings.Split(logs, "\n")
-
-	// Look for error indicators
-	errorKeywords := []string{"❌ FAIL", "ERROR", "FAIL", "panic", "fatal", "exit status"}
+	tr.mu.Lock()
+	tr.results = append(tr.results, results...)
+	tr.mu.Unlock()
+}
 
-	for i, line := range lines {
-		for _, keyword := range errorKeywords {
-			if strings.Contains(strings.ToUpper(line), keyword) {
-				// Return the error line and a few lines before and after for context
-				start := max(0, i-2)
-				end := min(len(lines), i+3)
-				return strings.Join(lines[start:end], "\n")
-			}
+// parseRunnerBytes returns the last value of a "NAME=<bytes>" line emitted by the runner
+func parseRunnerBytes(logs string, name string) (uint64, bool) {
+	var value uint64
+	found := false
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, name+"=")
+		if i < 0 {
+			continue
+		}
+		if parsed, err := strconv.ParseUint(strings.TrimSpace(line[i+len(name)+1:]), 10, 64); err == nil {
+			value = parsed
+			found = true
 		}
 	}
+	return value, found
+}
 
-	// If no error keywords found, return the last 10 lines
-	if len(lines) > 10 {
-		return strings.Join(lines[len(lines)-10:], "\n")
+// parseAllocPattern returns the allocation pattern the runner reported in its logs
+func parseAllocPattern(logs string) string {
+	const prefix = "Allocation pattern: "
+	for _, line := range strings.Split(logs, "\n") {
+		if i := strings.Index(line, prefix); i >= 0 {
+			return strings.TrimSpace(line[i+len(prefix):])
+		}
 	}
+	return ""
+}
 
-	return logs
+func getEnvAsIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
 }
 
 func max(a, b int) int {
@@ -477,7 +849,7 @@ func min(a, b int) int {
 }
 
 func main() {
-	// Define single sanity check test configuration
+	// Define sanity check test configurations
 	testConfigs := []TestConfig{
 		{
 			Name:             "sanity-check-test",
@@ -488,15 +860,287 @@ func main() {
 			EnvVars: map[string]string{
 				"ALLOC_SIZE_MB": "50",
 			},
+			FreezeTimeoutSeconds: 10,
+			Assertions: []StatAssertion{
+				{Field: "MemoryLimit", Equals: "536870912"},
+				{Field: "HeapLive", Min: "45M", Max: "60M"},
+				{Field: "IsMemLimitReached", Equals: "false"},
+			},
+		},
+		{
+			Name:             "spike-pattern-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"ALLOC_PATTERN": "spike",
+			},
+		},
+		{
+			Name:             "sawtooth-pattern-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"ALLOC_PATTERN": "sawtooth",
+			},
+		},
+		{
+			Name:             "churn-pattern-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"ALLOC_PATTERN": "churn",
+			},
+		},
+		{
+			Name:             "no-memory-limit-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"GOMEMLIMIT":    "off",
+			},
+			Assertions: []StatAssertion{
+				{Field: "MemoryLimit", Equals: "9223372036854775807"},
+				{Field: "IsMemLimitReached", Equals: "false"},
+			},
+		},
+		{
+			// rtml.Warmup on startup must make the controller ready before anything is allocated
+			Name:             "warmup-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"WARMUP":        "true",
+			},
+		},
+		{
+			// GOMEMLIMIT unset (the image sets it) and not derived from the cgroup:
+			// the runner must catch the misconfiguration instead of passing on the math.MaxInt64 sentinel
+			Name:             "unset-memory-limit-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 1,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB": "50",
+				"GOMEMLIMIT":    "",
+			},
+		},
+		{
+			// GOMEMLIMIT derived from the 512M container limit with rtml.AutoSetMemoryLimitFromCgroup, keeping 10% as reserve
+			Name:             "auto-memory-limit-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB":         "50",
+				"GOMEMLIMIT":            "",
+				"AUTO_MEMLIMIT_RESERVE": "0.1",
+			},
+			Assertions: []StatAssertion{
+				{Field: "MemoryLimit", Equals: "483183820"},
+				{Field: "IsMemLimitReached", Equals: "false"},
+			},
+		},
+		{
+			// GOMEMLIMIT set to 90% of the 512M container limit with rtml.SetMemoryLimitPercent
+			Name:             "percent-memory-limit-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"ALLOC_SIZE_MB":    "50",
+				"GOMEMLIMIT":       "",
+				"MEMLIMIT_PERCENT": "90",
+			},
+			Assertions: []StatAssertion{
+				{Field: "MemoryLimit", Equals: "483183820"},
+				{Field: "IsMemLimitReached", Equals: "false"},
+			},
+		},
+		{
+			// Stops allocating when IsMemLimitReached returns true, and must survive
+			Name:             "admission-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "256M",
+			TimeoutSeconds:   120,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE":     "admission",
+				"ALLOC_SIZE_MB": "1024",
+				"GOMEMLIMIT":    "200MiB",
+			},
+		},
+		{
+			// Same allocations ignoring IsMemLimitReached, which must end with an OOM kill
+			Name:             "admission-ignored-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "256M",
+			TimeoutSeconds:   120,
+			ExpectedExitCode: 137,
+			ExpectOOMKilled:  true,
+			EnvVars: map[string]string{
+				"TEST_MODE":               "admission",
+				"ALLOC_SIZE_MB":           "1024",
+				"GOMEMLIMIT":              "200MiB",
+				"ADMISSION_IGNORE_SIGNAL": "true",
+			},
+		},
+		{
+			// Holds more live memory than GOMEMLIMIT, within the container limit:
+			// IsMemLimitReached must turn true near the limit, and the final stats are taken there
+			Name:             "boundary-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "450M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE":     "boundary",
+				"ALLOC_SIZE_MB": "400",
+				"GOMEMLIMIT":    "360MiB",
+			},
+			Assertions: []StatAssertion{
+				{Field: "MemoryLimit", Equals: "377487360"},
+				{Field: "HeapLive", Min: "380M", Max: "440M"},
+				{Field: "IsMemLimitReached", Equals: "true"},
+			},
+		},
+		{
+			// Many goroutines allocating while IsMemLimitReached is polled at high frequency
+			Name:             "concurrency-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "256M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE":                "concurrency",
+				"ALLOC_SIZE_MB":            "400",
+				"GOMEMLIMIT":               "200MiB",
+				"CONCURRENCY_WORKERS":      "16",
+				"CONCURRENCY_DURATION_SEC": "10",
+			},
+		},
+		{
+			// Holds memory just below the limit, IsMemLimitReached must not flap
+			Name:             "soak-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "320M",
+			TimeoutSeconds:   90,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE":    "soak",
+				"SOAK_SECONDS": "30",
+				"GOMEMLIMIT":   "200MiB",
+			},
 		},
+		{
+			// Maps memory outside of the go heap, which rtml does not see: the container is OOM killed
+			// while IsMemLimitReached reports false, and the report flags it with oom_while_not_reached
+			Name:             "offheap-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "256M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 137,
+			ExpectOOMKilled:  true,
+			EnvVars: map[string]string{
+				"TEST_MODE":       "offheap",
+				"OFFHEAP_SIZE_MB": "512",
+				"GOMEMLIMIT":      "200MiB",
+			},
+		},
+		{
+			Name:             "benchmark-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE": "bench",
+			},
+			MaxNsPerOp: 50,
+		},
+		{
+			Name:               "cgroup-resize-test",
+			Image:              "go-rtml-test:latest",
+			MemoryLimit:        "512M",
+			TimeoutSeconds:     60,
+			ExpectedExitCode:   0,
+			ResizeMemoryTo:     "768M",
+			ResizeAfterSeconds: 2,
+			EnvVars: map[string]string{
+				"TEST_MODE":              "resize",
+				"RESIZE_WAIT_SEC":        "15",
+				"RESIZE_RESERVE_PERCENT": "10",
+			},
+			Assertions: []StatAssertion{
+				// 90% of the resized 768M limit, not of the initial 512M
+				{Field: "MemoryLimit", Equals: "724775731"},
+			},
+		},
+		{
+			// MappedReady - HeapFree follows memory.current after the runtime released memory
+			Name:             "resident-figure-test",
+			Image:            "go-rtml-test:latest",
+			MemoryLimit:      "512M",
+			TimeoutSeconds:   60,
+			ExpectedExitCode: 0,
+			EnvVars: map[string]string{
+				"TEST_MODE":     "resident",
+				"ALLOC_SIZE_MB": "200",
+			},
+		},
+	}
+
+	// "test-framework layout" only verifies the runtime struct layout, instead of the full suite
+	if len(os.Args) > 1 {
+		if os.Args[1] != layoutSubcommand {
+			log.Fatalf("Unknown subcommand %q, the only subcommand is %q", os.Args[1], layoutSubcommand)
+		}
+		testConfigs = layoutTestConfigs()
 	}
 
-	runner, err := NewTestRunner()
+	runtime := ContainerRuntime(os.Getenv("CONTAINER_RUNTIME"))
+	if runtime == "" {
+		runtime = RuntimeDocker
+	}
+
+	runner, err := NewTestRunner(runtime, getEnvAsIntOrDefault("TEST_CONCURRENCY", 1))
 	if err != nil {
 		log.Fatalf("Failed to create test runner: %v", err)
 	}
+	runner.SetStreamLogs(os.Getenv("TEST_STREAM_LOGS") == "true")
+	if budget := os.Getenv("TEST_MEMORY_BUDGET"); budget != "" {
+		memoryBudget, err := runner.parseMemoryLimit(budget)
+		if err != nil {
+			log.Fatalf("Invalid TEST_MEMORY_BUDGET: %v", err)
+		}
+		runner.SetMemoryBudget(memoryBudget)
+	}
 
 	ctx := context.Background()
+
+	// Run the whole suite against every Go version in GO_VERSIONS (e.g. "1.23,1.24,1.25")
+	if goVersions := os.Getenv("GO_VERSIONS"); goVersions != "" {
+		testConfigs = expandGoVersionMatrix(testConfigs, strings.Split(goVersions, ","))
+	}
+	testConfigs = runner.prepareImages(ctx, testConfigs)
+
 	runner.RunTestSuite(ctx, testConfigs)
 	runner.GenerateReport()
 }
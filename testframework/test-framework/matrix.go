@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Image tag of the runner built for a specific Go version
+const runnerImageRepository = "go-rtml-test"
+
+// expandGoVersionMatrix returns a copy of every config for each Go version.
+// The runtime struct mirror must match each Go version, so the same suite runs against all of them.
+func expandGoVersionMatrix(configs []TestConfig, goVersions []string) []TestConfig {
+	expanded := make([]TestConfig, 0, len(configs)*len(goVersions))
+	for _, goVersion := range goVersions {
+		goVersion = strings.TrimPrefix(strings.TrimSpace(goVersion), "go")
+		if goVersion == "" {
+			continue
+		}
+		for _, config := range configs {
+			config.GoVersion = goVersion
+			expanded = append(expanded, config)
+		}
+	}
+	return expanded
+}
+
+// prepareImages builds the runner image once for every Go version used by the configs,
+// and points those configs to it. Configs whose image failed to build are recorded as
+// failed results and left out of the returned list.
+func (tr *TestRunner) prepareImages(ctx context.Context, configs []TestConfig) []TestConfig {
+	images := make(map[string]string)
+	buildErrors := make(map[string]error)
+
+	prepared := make([]TestConfig, 0, len(configs))
+	for _, config := range configs {
+		if config.GoVersion == "" {
+			prepared = append(prepared, config)
+			continue
+		}
+
+		if _, built := images[config.GoVersion]; !built && buildErrors[config.GoVersion] == nil {
+			image, err := tr.buildRunnerImage(ctx, config.GoVersion)
+			if err != nil {
+				log.Printf("Failed to build runner image for Go %s: %v", config.GoVersion, err)
+				buildErrors[config.GoVersion] = err
+			} else {
+				images[config.GoVersion] = image
+			}
+		}
+
+		if err := buildErrors[config.GoVersion]; err != nil {
+			now := time.Now()
+			result := TestResult{
+				TestName:  config.Name,
+				GoVersion: config.GoVersion,
+				Status:    "failed",
+				StartTime: now,
+				EndTime:   now,
+				Error:     fmt.Sprintf("failed to build runner image: %v", err),
+			}
+			result.FailureDetails.Reason = "Image build failed"
+			result.FailureDetails.ActualValue = err.Error()
+
+			tr.mu.Lock()
+			tr.results = append(tr.results, result)
+			tr.mu.Unlock()
+			continue
+		}
+
+		config.Image = images[config.GoVersion]
+		prepared = append(prepared, config)
+	}
+	return prepared
+}
+
+// buildRunnerImage builds the runner with the golang base image of the given version, using build-docker.sh
+func (tr *TestRunner) buildRunnerImage(ctx context.Context, goVersion string) (string, error) {
+	image := fmt.Sprintf("%s:go%s", runnerImageRepository, goVersion)
+	log.Printf("Building runner image %s...", image)
+
+	cmd := exec.CommandContext(ctx, "./build-docker.sh", goVersion, image)
+	if tr.runtime == RuntimePodman {
+		cmd.Env = append(os.Environ(), "CONTAINER_CLI=podman")
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w\n%s", err, lastLines(string(output), 20))
+	}
+	return image, nil
+}
+
+func lastLines(text string, count int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printResultsByGoVersion prints the pass/fail counts of every Go version in the matrix
+func printResultsByGoVersion(results []TestResult) {
+	type versionSummary struct {
+		passed, failed int
+		failedTests    []string
+	}
+
+	summaries := make(map[string]*versionSummary)
+	for _, result := range results {
+		if result.GoVersion == "" {
+			continue
+		}
+		summary, exists := summaries[result.GoVersion]
+		if !exists {
+			summary = &versionSummary{}
+			summaries[result.GoVersion] = summary
+		}
+		if result.Status == "passed" {
+			summary.passed++
+		} else {
+			summary.failed++
+			summary.failedTests = append(summary.failedTests, result.TestName)
+		}
+	}
+	if len(summaries) == 0 {
+		return
+	}
+
+	versions := make([]string, 0, len(summaries))
+	for version := range summaries {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareGoVersions(versions[i], versions[j]) < 0
+	})
+
+	fmt.Printf("\n=== Results by Go Version ===\n")
+	for _, version := range versions {
+		summary := summaries[version]
+		if summary.failed == 0 {
+			fmt.Printf("✅ Go %s: %d passed\n", version, summary.passed)
+		} else {
+			fmt.Printf("❌ Go %s: %d passed, %d failed (%s)\n",
+				version, summary.passed, summary.failed, strings.Join(summary.failedTests, ", "))
+		}
+	}
+}
+
+// compareGoVersions compares versions like "1.23" and "1.24.1" numerically
+func compareGoVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			fmt.Sscanf(partsA[i], "%d", &numA)
+		}
+		if i < len(partsB) {
+			fmt.Sscanf(partsB[i], "%d", &numB)
+		}
+		if numA != numB {
+			return numA - numB
+		}
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Runners print "RTML_REACHED=<bool>" with the latest IsMemLimitReached result while they run
+const reachedMarker = "RTML_REACHED="
+
+// parseLastReached returns the last IsMemLimitReached result the runner reported,
+// and false when it reported none
+func parseLastReached(logs string) (reached bool, found bool) {
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, reachedMarker)
+		if i < 0 {
+			continue
+		}
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(line[i+len(reachedMarker):])); err == nil {
+			reached = parsed
+			found = true
+		}
+	}
+	return reached, found
+}
+
+// printOOMWhileNotReached lists the tests where the container was OOM killed while rtml reported "not reached",
+// the memory the OOM killer acted on was not visible to rtml (e.g. off-heap or cgo allocations)
+func printOOMWhileNotReached(results []TestResult) {
+	header := false
+	for _, result := range results {
+		if !result.OOMWhileNotReached {
+			continue
+		}
+		if !header {
+			fmt.Printf("\n=== OOM Killed While Not Reached ===\n")
+			header = true
+		}
+		fmt.Printf("⚠️  %s: OOM killed while IsMemLimitReached last reported false\n", result.TestName)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reporter writes the results of a test run somewhere: a file, the console, a CI dashboard...
+// Implement it to add an output format without changing GenerateReport.
+type Reporter interface {
+	Report(results []TestResult) error
+}
+
+const resultsDir = "test-results"
+
+// DefaultReporters are the outputs GenerateReport uses when no reporter is passed:
+// the JSON report, the console summary, the JUnit XML report and the HTML report, all under test-results/.
+func DefaultReporters() []Reporter {
+	return []Reporter{
+		JSONReporter{Path: filepath.Join(resultsDir, "test-report.json")},
+		StdoutReporter{},
+		JUnitReporter{Path: filepath.Join(resultsDir, "junit.xml")},
+		HTMLReporter{Path: filepath.Join(resultsDir, "report.html")},
+	}
+}
+
+// GenerateReport passes the results to every reporter, in order, or to DefaultReporters when none is passed.
+// A failing reporter is logged, and doesn't prevent the next ones from running.
+func (tr *TestRunner) GenerateReport(reporters ...Reporter) {
+	if len(reporters) == 0 {
+		reporters = DefaultReporters()
+	}
+
+	tr.mu.Lock()
+	results := append([]TestResult(nil), tr.results...)
+	tr.mu.Unlock()
+
+	for _, reporter := range reporters {
+		if err := reporter.Report(results); err != nil {
+			log.Printf("Failed to generate %T report: %v", reporter, err)
+		}
+	}
+}
+
+// JSONReporter writes the results, including the container logs, as an indented JSON array
+type JSONReporter struct {
+	Path string
+}
+
+func (r JSONReporter) Report(results []TestResult) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %w", err)
+	}
+	if err := os.WriteFile(r.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Printf("Report saved to: %s\n", r.Path)
+	return nil
+}
+
+// StdoutReporter prints the summary, the failure details with their log snippets, and the passed tests
+type StdoutReporter struct{}
+
+func (StdoutReporter) Report(results []TestResult) error {
+	// Generate detailed summary
+	passed := 0
+	failed := 0
+	timeout := 0
+
+	for _, result := range results {
+		switch result.Status {
+		case "passed":
+			passed++
+		case "failed":
+			failed++
+		case "timeout":
+			timeout++
+		}
+	}
+
+	fmt.Printf("\n=== Test Results Summary ===\n")
+	fmt.Printf("Total Tests: %d\n", len(results))
+	fmt.Printf("Passed: %d\n", passed)
+	fmt.Printf("Failed: %d\n", failed)
+	fmt.Printf("Timeout: %d\n", timeout)
+
+	printResultsByGoVersion(results)
+	printSoakResults(results)
+	printBenchResults(results)
+	printOOMWhileNotReached(results)
+
+	// Print detailed failure information
+	if failed > 0 || timeout > 0 {
+		fmt.Printf("\n=== Failure Details ===\n")
+		for _, result := range results {
+			if result.Status != "passed" {
+				if result.GoVersion != "" {
+					fmt.Printf("\n❌ Test: %s (Go %s)\n", result.TestName, result.GoVersion)
+				} else {
+					fmt.Printf("\n❌ Test: %s\n", result.TestName)
+				}
+				if result.OOMKilled {
+					fmt.Printf("   ⚠️  OOM KILLED: the container exceeded its memory limit and was killed by the kernel\n")
+				}
+				if result.OOMWhileNotReached {
+					fmt.Printf("   ⚠️  IsMemLimitReached last reported false before the OOM kill\n")
+				}
+				fmt.Printf("   Status: %s\n", result.Status)
+				if result.Pattern != "" {
+					fmt.Printf("   Allocation Pattern: %s\n", result.Pattern)
+				}
+				fmt.Printf("   Duration: %.2f seconds\n", result.Duration)
+				fmt.Printf("   Exit Code: %d\n", result.ExitCode)
+				if result.InfrastructureFailure {
+					fmt.Printf("   Infrastructure failure (docker), not a test assertion\n")
+				}
+				if len(result.Attempts) > 1 {
+					fmt.Printf("   Attempts: %d\n", len(result.Attempts))
+				}
+				fmt.Printf("   Error: %s\n", result.Error)
+
+				if result.FailureDetails.Reason != "" {
+					fmt.Printf("   Reason: %s\n", result.FailureDetails.Reason)
+					if result.FailureDetails.ExpectedValue != "" {
+						fmt.Printf("   Expected: %s\n", result.FailureDetails.ExpectedValue)
+					}
+					if result.FailureDetails.ActualValue != "" {
+						fmt.Printf("   Actual: %s\n", result.FailureDetails.ActualValue)
+					}
+				}
+
+				if result.FailureDetails.LogSnippet != "" {
+					fmt.Printf("   Log Snippet:\n")
+					lines := strings.Split(result.FailureDetails.LogSnippet, "\n")
+					for _, line := range lines {
+						if strings.TrimSpace(line) != "" {
+							fmt.Printf("     %s\n", line)
+						}
+					}
+				}
+
+				if result.MemoryStats.PeakMemoryMB > 0 {
+					fmt.Printf("   Peak Memory: %.2f MB\n", result.MemoryStats.PeakMemoryMB)
+				}
+			}
+		}
+	}
+
+	// Print success information
+	if passed > 0 {
+		fmt.Printf("\n=== Success Details ===\n")
+		for _, result := range results {
+			if result.Status == "passed" {
+				fmt.Printf("✅ Test: %s (%.2fs, Peak: %.2f MB)\n",
+					result.TestName, result.Duration, result.MemoryStats.PeakMemoryMB)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// resizeContainerMemory changes the memory limit of a running container after delay,
+// like a kubernetes in-place pod resize, for the runner to detect the new cgroup limit.
+// Failures are only logged: the runner then reports that the limit never changed.
+func (tr *TestRunner) resizeContainerMemory(ctx context.Context, containerID string, delay time.Duration, memory, memorySwap int64) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+
+	_, err := tr.dockerClient.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		Resources: container.Resources{
+			Memory:     memory,
+			MemorySwap: memorySwap,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to resize the memory of container %s: %v", containerID[:12], err)
+		return
+	}
+	log.Printf("Resized the memory of container %s to %d MB", containerID[:12], memory/(1024*1024))
+}
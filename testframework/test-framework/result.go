@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The runner ends with "RTML_RESULT status=pass", or with
+// "RTML_RESULT status=fail reason=<quoted> field=<quoted> expected=<quoted> actual=<quoted>" when a check failed
+const resultMarker = "RTML_RESULT "
+
+// RunnerResult is the outcome the runner reported on its RTML_RESULT line
+type RunnerResult struct {
+	Status   string `json:"status"` // "pass" or "fail"
+	Reason   string `json:"reason,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// parseResultLine returns the last RTML_RESULT line in the logs,
+// and false when the runner reported none (e.g. it crashed, or was OOM killed)
+func parseResultLine(logs string) (RunnerResult, bool) {
+	var result RunnerResult
+	found := false
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, resultMarker)
+		if i < 0 {
+			continue
+		}
+		fields := parseQuotedFields(line[i+len(resultMarker):])
+		result = RunnerResult{
+			Status:   fields["status"],
+			Reason:   fields["reason"],
+			Field:    fields["field"],
+			Expected: fields["expected"],
+			Actual:   fields["actual"],
+		}
+		found = true
+	}
+	return result, found
+}
+
+// parseQuotedFields parses "key=value key="quoted value" ..." pairs, values are either bare words or Go quoted strings
+func parseQuotedFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t\r")
+		name, rest, found := strings.Cut(s, "=")
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return fields
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return fields
+			}
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				return fields
+			}
+			fields[name] = value
+			s = rest[len(quoted):]
+			continue
+		}
+
+		value, next, _ := strings.Cut(rest, " ")
+		fields[name] = strings.TrimSpace(value)
+		s = next
+	}
+}
+
+// failed reports whether the runner reported a failed check
+func (r RunnerResult) failed() bool {
+	return r.Status == "fail"
+}
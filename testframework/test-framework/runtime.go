@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+)
+
+// ContainerRuntime selects the engine the framework talks to.
+// Podman serves a docker compatible API, so only the socket discovery differs.
+type ContainerRuntime string
+
+const (
+	RuntimeDocker ContainerRuntime = "docker"
+	RuntimePodman ContainerRuntime = "podman"
+)
+
+// newContainerClient creates an API client for the runtime.
+//
+// DOCKER_HOST (and the other DOCKER_* variables) always take precedence.
+// For podman, CONTAINER_HOST is honored next, and otherwise the rootless socket
+// ($XDG_RUNTIME_DIR/podman/podman.sock) or the rootful one (/run/podman/podman.sock) is used.
+func newContainerClient(runtime ContainerRuntime) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+
+	switch runtime {
+	case RuntimeDocker, "":
+	case RuntimePodman:
+		if os.Getenv("DOCKER_HOST") == "" {
+			host, err := podmanHost()
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, client.WithHost(host))
+		}
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q (expected %q or %q)", runtime, RuntimeDocker, RuntimePodman)
+	}
+
+	return client.NewClientWithOpts(opts...)
+}
+
+// podmanHost returns the address of the podman API socket
+func podmanHost() (string, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, nil
+	}
+
+	var sockets []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	sockets = append(sockets, "/run/podman/podman.sock")
+
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket, nil
+		}
+	}
+	return "", fmt.Errorf("podman socket not found in %v, start it with 'systemctl --user start podman.socket' or set CONTAINER_HOST", sockets)
+}
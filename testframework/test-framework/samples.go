@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The runner prints "RTML_SAMPLE t=<ms> heapLive=<bytes> mappedReady=<bytes> heapGoal=<bytes> totalAlloc=<bytes> allocating=<bool>"
+// while it runs
+const sampleLinePrefix = "RTML_SAMPLE "
+
+// MemorySample is a point of the memory time series reported by the runner,
+// t_ms is the time since the runner started
+type MemorySample struct {
+	TimeMs      uint64 `json:"t_ms"`
+	HeapLive    uint64 `json:"heap_live"`
+	MappedReady uint64 `json:"mapped_ready"`
+	HeapGoal    uint64 `json:"heap_goal"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+
+	// Whether the runner was allocating when the sample was taken, see the freeze watchdog
+	Allocating bool `json:"allocating,omitempty"`
+}
+
+func (s MemorySample) String() string {
+	return fmt.Sprintf("t=%dms heapLive=%d mappedReady=%d heapGoal=%d totalAlloc=%d allocating=%t",
+		s.TimeMs, s.HeapLive, s.MappedReady, s.HeapGoal, s.TotalAlloc, s.Allocating)
+}
+
+// parseSamples returns the samples reported in the logs, in order, or nil when there are none
+func parseSamples(logs string) []MemorySample {
+	var samples []MemorySample
+	for _, line := range strings.Split(logs, "\n") {
+		if sample, ok := parseSampleLine(line); ok {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// parseSampleLine parses a single RTML_SAMPLE line, and returns false for any other line
+func parseSampleLine(line string) (MemorySample, bool) {
+	i := strings.Index(line, sampleLinePrefix)
+	if i < 0 {
+		return MemorySample{}, false
+	}
+	var sample MemorySample
+	for _, field := range strings.Fields(line[i+len(sampleLinePrefix):]) {
+		name, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		if name == "allocating" {
+			sample.Allocating, _ = strconv.ParseBool(value)
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "t":
+			sample.TimeMs = parsed
+		case "heapLive":
+			sample.HeapLive = parsed
+		case "mappedReady":
+			sample.MappedReady = parsed
+		case "heapGoal":
+			sample.HeapGoal = parsed
+		case "totalAlloc":
+			sample.TotalAlloc = parsed
+		}
+	}
+	return sample, true
+}
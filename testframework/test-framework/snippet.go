@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LogSnippetConfig controls which part of the logs is kept as the snippet of a failed test
+type LogSnippetConfig struct {
+	// Keywords in priority order, matched case insensitively: the snippet is taken around
+	// the first line containing the first keyword found in the logs
+	Keywords []string
+
+	// Lines kept before and after the matching line (and its indented detail lines)
+	ContextBefore int
+	ContextAfter  int
+
+	// Lines kept from the end of the logs when no keyword matches
+	TailLines int
+}
+
+// DefaultLogSnippetConfig anchors on the markers the runner prints first, and falls back to generic error keywords.
+// "❌ FAIL" is followed by the "Expected / Got" lines of the failing check, and the RTML_STAT line
+// holds the values the framework assertions failed on.
+func DefaultLogSnippetConfig() LogSnippetConfig {
+	return LogSnippetConfig{
+		Keywords:      []string{"❌ FAIL", "panic:", "fatal error:", statLinePrefix, "ERROR", "FAIL", "panic", "fatal", "exit status"},
+		ContextBefore: 2,
+		ContextAfter:  2,
+		TailLines:     10,
+	}
+}
+
+// SetLogSnippetConfig changes how the log snippet of failed tests is extracted
+func (tr *TestRunner) SetLogSnippetConfig(config LogSnippetConfig) {
+	tr.logSnippet = config
+}
+
+// The timestamp the runner log lines start with (log.LstdFlags | log.Lmicroseconds)
+var logTimestamp = regexp.MustCompile(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// isDetailLine reports whether the log line is indented, like the "   Expected:" lines following a failure
+func isDetailLine(line string) bool {
+	if loc := logTimestamp.FindStringIndex(line); loc != nil {
+		line = line[loc[1]:]
+	}
+	return strings.HasPrefix(line, "  ")
+}
+
+// extractRelevantLogSnippet extracts the most relevant part of logs for debugging
+func (tr *TestRunner) extractRelevantLogSnippet(logs string) string {
+	if logs == "" {
+		return ""
+	}
+
+	config := tr.logSnippet
+	lines := strings.Split(logs, "\n")
+
+	for _, keyword := range config.Keywords {
+		keyword = strings.ToUpper(keyword)
+		for i, line := range lines {
+			if !strings.Contains(strings.ToUpper(line), keyword) {
+				continue
+			}
+
+			// Keep the detail lines of the match (expected/actual values), then the context after them
+			end := i + 1
+			for end < len(lines) && isDetailLine(lines[end]) {
+				end++
+			}
+			start := max(0, i-config.ContextBefore)
+			end = min(len(lines), end+config.ContextAfter)
+			return strings.Join(lines[start:end], "\n")
+		}
+	}
+
+	// If no keyword is found, return the last lines
+	if config.TailLines > 0 && len(lines) > config.TailLines {
+		return strings.Join(lines[len(lines)-config.TailLines:], "\n")
+	}
+
+	return logs
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The soak test prints "RTML_SOAK Samples=... Reached=... Flips=... DurationSeconds=..."
+const soakLinePrefix = "RTML_SOAK "
+
+// SoakStats summarizes how IsMemLimitReached behaved during a soak test
+type SoakStats struct {
+	Samples         uint64  `json:"samples"`
+	Reached         uint64  `json:"reached"`
+	Flips           uint64  `json:"flips"`
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Largest relative difference between the rtml stats and runtime/metrics, and the field it was observed on
+	MaxDiscrepancy      float64 `json:"max_discrepancy"`
+	MaxDiscrepancyField string  `json:"max_discrepancy_field,omitempty"`
+
+	// Comparisons where IsMemLimitReached disagreed with the decision made from runtime/metrics
+	DecisionMismatches uint64 `json:"decision_mismatches"`
+}
+
+// parseSoakLine returns the soak results reported in the logs, or nil when the test is not a soak test
+func parseSoakLine(logs string) *SoakStats {
+	var soak *SoakStats
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, soakLinePrefix)
+		if i < 0 {
+			continue
+		}
+		soak = &SoakStats{}
+		for _, field := range strings.Fields(line[i+len(soakLinePrefix):]) {
+			name, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+			switch name {
+			case "Samples":
+				soak.Samples, _ = strconv.ParseUint(value, 10, 64)
+			case "Reached":
+				soak.Reached, _ = strconv.ParseUint(value, 10, 64)
+			case "Flips":
+				soak.Flips, _ = strconv.ParseUint(value, 10, 64)
+			case "DurationSeconds":
+				soak.DurationSeconds, _ = strconv.ParseFloat(value, 64)
+			case "MaxDiscrepancy":
+				soak.MaxDiscrepancy, _ = strconv.ParseFloat(value, 64)
+			case "MaxDiscrepancyField":
+				soak.MaxDiscrepancyField = value
+			case "DecisionMismatches":
+				soak.DecisionMismatches, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return soak
+}
+
+// printSoakResults prints the flip count of every soak test, and how accurate the stats were compared with runtime/metrics
+func printSoakResults(results []TestResult) {
+	header := false
+	for _, result := range results {
+		if result.Soak == nil {
+			continue
+		}
+		if !header {
+			fmt.Printf("\n=== Soak Results ===\n")
+			header = true
+		}
+		reachedPercent := 0.0
+		if result.Soak.Samples > 0 {
+			reachedPercent = float64(result.Soak.Reached) * 100 / float64(result.Soak.Samples)
+		}
+		fmt.Printf("%s: %d flips in %.0f seconds, limit reached in %.1f%% of %d samples\n",
+			result.TestName, result.Soak.Flips, result.Soak.DurationSeconds, reachedPercent, result.Soak.Samples)
+		if result.Soak.MaxDiscrepancyField != "" {
+			fmt.Printf("   max discrepancy with runtime/metrics: %.4f%% (%s), decision mismatches: %d\n",
+				result.Soak.MaxDiscrepancy*100, result.Soak.MaxDiscrepancyField, result.Soak.DecisionMismatches)
+		}
+	}
+}
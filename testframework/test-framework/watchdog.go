@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// How often the watchdog checks for a freeze when no new line arrives
+const freezeCheckInterval = time.Second
+
+// watchForFreeze follows the container output and sends the last sample on frozen when the runner claims to be
+// allocating (RTML_SAMPLE allocating=true) while TotalAlloc did not advance for freezeTimeout.
+// The sample lines stopping altogether, with the last one still allocating, counts as a freeze as well,
+// so a hung runner fails fast with the last progress it made, instead of waiting for the test timeout.
+// It stops when the container exits or ctx is done.
+func (tr *TestRunner) watchForFreeze(ctx context.Context, containerID string, freezeTimeout time.Duration, frozen chan<- MemorySample) {
+	logs, err := tr.dockerClient.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		log.Printf("Failed to follow logs for the freeze watchdog: %v", err)
+		return
+	}
+	defer logs.Close()
+
+	// Without a TTY, stdout and stderr are multiplexed in a single stream
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(writer, writer, logs)
+		writer.CloseWithError(err)
+	}()
+
+	samples := make(chan MemorySample)
+	go func() {
+		defer close(samples)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if sample, ok := parseSampleLine(scanner.Text()); ok {
+				select {
+				case samples <- sample:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(freezeCheckInterval)
+	defer ticker.Stop()
+
+	var last MemorySample
+	seen := false
+	lastProgress := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sample, ok := <-samples:
+			if !ok {
+				return
+			}
+			if !seen || sample.TotalAlloc != last.TotalAlloc || !sample.Allocating {
+				lastProgress = time.Now()
+			}
+			last, seen = sample, true
+		case <-ticker.C:
+		}
+
+		if seen && last.Allocating && time.Since(lastProgress) >= freezeTimeout {
+			frozen <- last
+			return
+		}
+	}
+}
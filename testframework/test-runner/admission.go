@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+const admissionChunkSize = 1024 * 1024
+
+// runAdmissionTest allocates up to test.allocSizeMB of live memory as fast as possible,
+// checking IsMemLimitReached before every chunk like a service would before accepting work.
+//
+// By default it stops allocating as soon as the limit is reached, and the container must survive
+// (this is the claim the library makes). With ADMISSION_IGNORE_SIGNAL=true it keeps allocating
+// regardless, and the container is expected to be OOM killed, which proves the signal was meaningful.
+func runAdmissionTest(test SanityTest, ignoreSignal bool) {
+	log.Printf("Running admission test (ignore signal: %t)...", ignoreSignal)
+
+	if limit := rtml.GetMemLimitRelatedStats().MemoryLimit; limit == 0 || limit == math.MaxInt64 {
+		failf("the admission test requires GOMEMLIMIT to be set below the container limit")
+	}
+
+	rtml.ResetPeak()
+
+	numChunks := mbToBytes(test.allocSizeMB) / admissionChunkSize
+	globalChunks = make([][]byte, 0, numChunks)
+	signaledAt := -1
+	for i := uint64(0); i < numChunks; i++ {
+		if rtml.IsMemLimitReached() {
+			if signaledAt < 0 {
+				signaledAt = len(globalChunks)
+				stats := rtml.GetMemLimitRelatedStats()
+				log.Printf("IsMemLimitReached returned true after %d MB: %s", signaledAt, stats)
+			}
+			if !ignoreSignal {
+				break
+			}
+		}
+		globalChunks = append(globalChunks, allocateChunk(i, admissionChunkSize))
+	}
+
+	if signaledAt < 0 {
+		failf("allocated %d MB without IsMemLimitReached ever returning true", test.allocSizeMB)
+	}
+	if ignoreSignal {
+		// the container should have been OOM killed before getting here
+		failf("allocated %d MB ignoring the signal, and was not OOM killed", bytesToMB(uint64(len(globalChunks))*admissionChunkSize))
+	}
+
+	log.Printf("✅ Stopped allocating at %d MB when the memory limit was reached, and was not OOM killed", signaledAt)
+
+	// The limit was reached, so the tracked peak must have come close to it
+	if peak := rtml.PeakLimitRatio(); peak < 0.8 {
+		failf("PeakLimitRatio is %.2f after reaching the memory limit", peak)
+	} else {
+		log.Printf("✅ PeakLimitRatio is %.2f", peak)
+	}
+}
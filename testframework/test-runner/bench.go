@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// Prevent the compiler from optimizing away the benchmarked calls
+var (
+	benchReached bool
+	benchStats   rtml.MemLimitRelatedStats
+)
+
+// runBenchmarks measures the hot path functions of the library.
+// The results guide whether any caching is warranted, and guard against accidental slowdowns.
+func runBenchmarks() {
+	log.Println("Running benchmarks...")
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"BenchmarkIsMemLimitReached", benchmarkIsMemLimitReached},
+		{"BenchmarkGetMemLimitRelatedStats", benchmarkGetMemLimitRelatedStats},
+		{"BenchmarkGetMemLimitRelatedStatsInto", benchmarkGetMemLimitRelatedStatsInto},
+		{"BenchmarkIsMemLimitReachedParallel", benchmarkIsMemLimitReachedParallel},
+	}
+
+	for _, bm := range benchmarks {
+		result := testing.Benchmark(bm.fn)
+		log.Printf("%s\t%s\t%s", bm.name, result.String(), result.MemString())
+
+		// The framework gates the overhead of the hot path on this line
+		if bm.name == "BenchmarkIsMemLimitReached" {
+			fmt.Printf("%s ns_per_op=%.2f iterations=%d allocs_per_op=%d\n",
+				benchMarker, float64(result.T.Nanoseconds())/float64(result.N), result.N, result.AllocsPerOp())
+		}
+	}
+}
+
+func benchmarkIsMemLimitReached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchReached = rtml.IsMemLimitReached()
+	}
+}
+
+func benchmarkGetMemLimitRelatedStats(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchStats = rtml.GetMemLimitRelatedStats()
+	}
+}
+
+// Fills the same struct on every call, which must not allocate
+func benchmarkGetMemLimitRelatedStatsInto(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rtml.GetMemLimitRelatedStatsInto(&benchStats)
+	}
+}
+
+// Measures contention on the runtime atomics when many goroutines check the limit concurrently
+func benchmarkIsMemLimitReachedParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var reached bool
+		for pb.Next() {
+			reached = rtml.IsMemLimitReached()
+		}
+		benchReached = reached
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+const boundaryChunkSize = 4 * 1024 * 1024
+
+// While holding the memory over the limit, the pressure must stay critical long enough
+// for an OnSustainedCritical hook to fire within the wait
+const (
+	sustainedCriticalDuration = time.Second
+	sustainedCriticalWait     = 3 * time.Second
+	sustainedCriticalInterval = 100 * time.Millisecond
+)
+
+// runBoundaryTest allocates test.allocSizeMB of live memory, past GOMEMLIMIT but within the container limit,
+// and checks IsMemLimitReached after every chunk, printing "RTML_REACHED=<bool>" so the transition shows in the logs.
+//
+// Unlike the admission test, it keeps allocating after the signal and holds the memory, so the final
+// RTML_STAT line is taken at the limit: IsMemLimitReached must have turned true as the allocations
+// approached the limit (not way before it), and must still be true at the end. Holding the memory,
+// the critical pressure is sustained, so an OnSustainedCritical hook must fire.
+func runBoundaryTest(test SanityTest) {
+	log.Println("Running memory limit boundary test...")
+
+	limit := rtml.GetMemLimitRelatedStats().MemoryLimit
+	if limit == 0 || limit == math.MaxInt64 {
+		failf("the boundary test requires GOMEMLIMIT to be set below the allocation size")
+	}
+
+	numChunks := mbToBytes(test.allocSizeMB) / boundaryChunkSize
+	globalChunks = make([][]byte, 0, numChunks)
+	reachedAt := uint64(0)
+	for i := uint64(0); i < numChunks; i++ {
+		globalChunks = append(globalChunks, allocateChunk(i, boundaryChunkSize))
+
+		reached := rtml.IsMemLimitReached()
+		fmt.Printf("%s=%t\n", reachedMarker, reached)
+		if reached && reachedAt == 0 {
+			reachedAt = (i + 1) * boundaryChunkSize
+			log.Printf("IsMemLimitReached turned true at %d MB of live memory (limit %d MB): %s",
+				bytesToMB(reachedAt), bytesToMB(limit), rtml.GetMemLimitRelatedStats())
+		}
+	}
+
+	if reachedAt == 0 {
+		failf("allocated %d MB past the %d MB limit without IsMemLimitReached turning true",
+			test.allocSizeMB, bytesToMB(limit))
+	}
+	if minReachedAt := limit * 8 / 10; reachedAt < minReachedAt {
+		failf("IsMemLimitReached turned true at %d MB, before 80%% of the %d MB limit",
+			bytesToMB(reachedAt), bytesToMB(limit))
+	}
+	if !rtml.IsMemLimitReached() {
+		failf("IsMemLimitReached is false while holding %d MB over the %d MB limit",
+			test.allocSizeMB, bytesToMB(limit))
+	}
+
+	log.Printf("✅ IsMemLimitReached turned true at %d MB of the %d MB limit, and stayed true while holding %d MB",
+		bytesToMB(reachedAt), bytesToMB(limit), test.allocSizeMB)
+
+	checkSustainedCritical()
+}
+
+// checkSustainedCritical holds the memory and waits for an OnSustainedCritical hook to fire
+func checkSustainedCritical() {
+	fired := make(chan struct{})
+	start := time.Now()
+	monitor := rtml.NewMonitor(sustainedCriticalInterval, nil,
+		rtml.OnSustainedCritical(sustainedCriticalDuration, func() { close(fired) }))
+	monitor.Start()
+	defer monitor.Stop()
+
+	select {
+	case <-fired:
+		log.Printf("✅ OnSustainedCritical fired after %v of critical pressure", time.Since(start).Round(time.Millisecond))
+	case <-time.After(sustainedCriticalWait):
+		failf("OnSustainedCritical(%v) did not fire within %v while holding the memory over the limit (level %s)",
+			sustainedCriticalDuration, sustainedCriticalWait, monitor.Level())
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"log"
+	"runtime"
+)
+
+// lockChunk is a no-op where mlock is not available, the pages are committed by touching them only
+func lockChunk(chunk []byte) {}
+
+// logProcessMemory logs the memory the runtime obtained from the OS, since there is no /proc/self/status
+func logProcessMemory() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	log.Printf("Process memory from the runtime (no /proc on %s): Sys=%d MB", runtime.GOOS, bytesToMB(memStats.Sys))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+const concurrencyChunkSize = 256 * 1024
+
+// concurrencyCounters are shared by the workers and the poller of the concurrency test
+type concurrencyCounters struct {
+	allocated atomic.Uint64
+	sheds     atomic.Uint64
+	polls     atomic.Uint64
+	reached   atomic.Uint64
+	panics    atomic.Uint64
+}
+
+// runConcurrencyTest allocates from many goroutines at once, while another goroutine polls
+// IsMemLimitReached as fast as it can, to validate the thread safety claims under realistic contention.
+//
+// Every worker keeps up to its share of ALLOC_SIZE_MB live, replacing its oldest chunk once the share is full,
+// and drops all of its chunks when IsMemLimitReached returns true (like a service shedding work).
+// The memory limit is expected to be reached and recovered from repeatedly.
+// The test fails if any call panics, or if the peak RSS reached the container memory limit.
+func runConcurrencyTest(test SanityTest, workers int, duration time.Duration) {
+	log.Printf("Running concurrency test with %d workers for %v...", workers, duration)
+
+	var counters concurrencyCounters
+	deadline := time.Now().Add(duration)
+	perWorkerChunks := max(int(mbToBytes(test.allocSizeMB)/concurrencyChunkSize)/workers, 1)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer recoverConcurrencyPanic(&counters, fmt.Sprintf("worker %d", w))
+			runConcurrencyWorker(&counters, w, perWorkerChunks, deadline)
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer recoverConcurrencyPanic(&counters, "poller")
+		for time.Now().Before(deadline) {
+			// check the clock only every so often, so the loop is dominated by IsMemLimitReached
+			for i := 0; i < 1000; i++ {
+				if rtml.IsMemLimitReached() {
+					counters.reached.Add(1)
+				}
+			}
+			counters.polls.Add(1000)
+		}
+	}()
+	wg.Wait()
+
+	log.Printf("Workers allocated %d MB in total, and shed their live memory %d times",
+		bytesToMB(counters.allocated.Load()), counters.sheds.Load())
+	log.Printf("Poller called IsMemLimitReached %d times, %d returned true", counters.polls.Load(), counters.reached.Load())
+
+	if panics := counters.panics.Load(); panics > 0 {
+		failf("%d goroutines panicked", panics)
+	}
+	log.Printf("✅ No panics under contention")
+
+	containerLimit, err := rtml.CgroupMemoryMax()
+	if err != nil {
+		log.Printf("Container memory limit not available, skipping the peak RSS check: %v", err)
+		return
+	}
+	peakRSS, err := readProcStatusBytes("VmHWM")
+	if err != nil {
+		log.Printf("Peak RSS not available, skipping the peak RSS check: %v", err)
+		return
+	}
+	if peakRSS >= containerLimit {
+		failf("peak RSS %d MB reached the container memory limit %d MB", bytesToMB(peakRSS), bytesToMB(containerLimit))
+	}
+	log.Printf("✅ Peak RSS %d MB stayed under the container memory limit %d MB", bytesToMB(peakRSS), bytesToMB(containerLimit))
+}
+
+func runConcurrencyWorker(counters *concurrencyCounters, w int, maxChunks int, deadline time.Time) {
+	chunks := make([][]byte, 0, maxChunks)
+	for i := uint64(0); time.Now().Before(deadline); i++ {
+		if rtml.IsMemLimitReached() {
+			if len(chunks) > 0 {
+				clear(chunks)
+				chunks = chunks[:0]
+				counters.sheds.Add(1)
+			}
+			// the shed chunks are only reclaimed by the next collection, which nobody triggers while everyone waits
+			rtml.ForceGCIfNearLimit(0.9)
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		chunk := allocateChunk(uint64(w)+i, concurrencyChunkSize)
+		if len(chunks) < maxChunks {
+			chunks = append(chunks, chunk)
+		} else {
+			chunks[i%uint64(maxChunks)] = chunk
+		}
+		counters.allocated.Add(concurrencyChunkSize)
+	}
+}
+
+func recoverConcurrencyPanic(counters *concurrencyCounters, name string) {
+	if r := recover(); r != nil {
+		counters.panics.Add(1)
+		log.Printf("❌ %s panicked: %v", name, r)
+	}
+}
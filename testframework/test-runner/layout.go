@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"strings"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// runLayoutTest only verifies the runtime struct mirror, without allocating.
+// main already failed if rtml.LayoutVerified returned an error, this adds the checks
+// that a mismatch wasn't swallowed, and logs what the mirror was verified against.
+// It is a fast gate for the Go version matrix, catching struct drift without the full allocation tests.
+func runLayoutTest() {
+	log.Println("Running layout verification test...")
+
+	supported := rtml.SupportedGoVersions()
+	log.Printf("Mirror verified for: %s", strings.Join(supported, ", "))
+
+	if !rtml.IsSupported() {
+		failf("IsSupported is false on %s", runtime.Version())
+	}
+	log.Printf("✅ IsSupported is true")
+
+	stats := rtml.GetMemLimitRelatedStats()
+	if stats.MemoryLimit == 0 || stats.MappedReady == 0 {
+		failf("stats read through the mirror are zero (MemoryLimit %d, MappedReady %d)",
+			stats.MemoryLimit, stats.MappedReady)
+	}
+	log.Printf("✅ Layout verified on %s", runtime.Version())
+}
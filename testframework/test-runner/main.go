@@ -1,21 +1,71 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"runtime"
 	"strconv"
-	"strings"
-	"syscall"
 	"time"
 
 	rtml "github.com/odigos-io/go-rtml"
 )
 
 type SanityTest struct {
-	allocSizeMB uint64
+	allocSizeMB  uint64
+	allocPattern string
+	allocCycles  int
+	thresholds   SanityThresholds
+
+	// how often the sanity check prints an RTML_SAMPLE line (SAMPLE_INTERVAL_MS)
+	sampleInterval time.Duration
+
+	// how the memory limit was derived from the cgroup (MEMLIMIT_PERCENT, AUTO_MEMLIMIT_RESERVE), nil for GOMEMLIMIT
+	derivedMemoryLimit *derivedMemoryLimit
+}
+
+// SanityThresholds are the bounds the sanity check asserts on the final stats.
+// Each can be overridden with an environment variable, so different test configs
+// can assert different expectations without rebuilding the runner image.
+type SanityThresholds struct {
+	heapLiveMinPercent       uint64 // SANITY_HEAP_LIVE_MIN_PERCENT, of allocated memory
+	heapLiveMaxPercent       uint64 // SANITY_HEAP_LIVE_MAX_PERCENT, of allocated memory
+	mappedReadyMinOverheadMB uint64 // SANITY_MAPPED_READY_MIN_OVERHEAD_MB, above HeapLive
+	mappedReadyMaxOverheadMB uint64 // SANITY_MAPPED_READY_MAX_OVERHEAD_MB, above HeapLive
+	heapGoalMaxGrowthMB      uint64 // SANITY_HEAP_GOAL_MAX_GROWTH_MB, above HeapLive
+	totalAllocMinPercent     uint64 // SANITY_TOTAL_ALLOC_MIN_PERCENT, of allocated memory
+	totalAllocMaxPercent     uint64 // SANITY_TOTAL_ALLOC_MAX_PERCENT, of allocated memory
+	totalFreeMaxMB           uint64 // SANITY_TOTAL_FREE_MAX_MB
 }
 
+func parseSanityThresholds() SanityThresholds {
+	return SanityThresholds{
+		heapLiveMinPercent:       uint64(getEnvAsIntOrDefault("SANITY_HEAP_LIVE_MIN_PERCENT", 90)),
+		heapLiveMaxPercent:       uint64(getEnvAsIntOrDefault("SANITY_HEAP_LIVE_MAX_PERCENT", 120)),
+		mappedReadyMinOverheadMB: uint64(getEnvAsIntOrDefault("SANITY_MAPPED_READY_MIN_OVERHEAD_MB", 2)),
+		mappedReadyMaxOverheadMB: uint64(getEnvAsIntOrDefault("SANITY_MAPPED_READY_MAX_OVERHEAD_MB", 10)),
+		heapGoalMaxGrowthMB:      uint64(getEnvAsIntOrDefault("SANITY_HEAP_GOAL_MAX_GROWTH_MB", 60)),
+		totalAllocMinPercent:     uint64(getEnvAsIntOrDefault("SANITY_TOTAL_ALLOC_MIN_PERCENT", 90)),
+		totalAllocMaxPercent:     uint64(getEnvAsIntOrDefault("SANITY_TOTAL_ALLOC_MAX_PERCENT", 120)),
+		totalFreeMaxMB:           uint64(getEnvAsIntOrDefault("SANITY_TOTAL_FREE_MAX_MB", 5)),
+	}
+}
+
+// Test modes selected with the TEST_MODE environment variable
+const (
+	testModeSanity      = "sanity"
+	testModeBench       = "bench"
+	testModeAdmission   = "admission"
+	testModeConcurrency = "concurrency"
+	testModeSoak        = "soak"
+	testModeLayout      = "layout"
+	testModeOffHeap     = "offheap"
+	testModeBoundary    = "boundary"
+	testModeResize      = "resize"
+	testModeResident    = "resident"
+)
+
 // Global variable to keep chunks alive
 var globalChunks [][]byte
 
@@ -25,13 +75,50 @@ func main() {
 
 	// Parse environment variables
 	test := SanityTest{
-		allocSizeMB: uint64(getEnvAsIntOrDefault("ALLOC_SIZE_MB", 50)),
+		allocSizeMB:  uint64(getEnvAsIntOrDefault("ALLOC_SIZE_MB", 50)),
+		allocPattern: getEnvOrDefault("ALLOC_PATTERN", allocPatternLinear),
+		allocCycles:  max(getEnvAsIntOrDefault("ALLOC_CYCLES", 3), 1),
+		thresholds:   parseSanityThresholds(),
+
+		sampleInterval: time.Duration(max(getEnvAsIntOrDefault("SAMPLE_INTERVAL_MS", 50), 1)) * time.Millisecond,
 	}
 
-	log.Printf("=== Starting sanity check test ===")
+	progressLogInterval = time.Duration(max(getEnvAsIntOrDefault("PROGRESS_LOG_INTERVAL_MS", int(progressLogInterval/time.Millisecond)), 0)) * time.Millisecond
+
+	log.Printf("=== Starting %s test ===", getEnvOrDefault("TEST_MODE", testModeSanity))
 	log.Printf("Go version: %s", runtime.Version())
 	log.Printf("Allocation size: %d MB", test.allocSizeMB)
+	log.Printf("Allocation pattern: %s", test.allocPattern)
 	log.Printf("Available CPUs: %d", runtime.NumCPU())
+
+	// Verify the runtime struct mirror before trusting any of the stats
+	if err := rtml.LayoutVerified(); err != nil {
+		failf("%v", err)
+	}
+	log.Printf("✅ Runtime struct layout verified")
+
+	if !isValidAllocPattern(test.allocPattern) {
+		failf("unknown ALLOC_PATTERN %q", test.allocPattern)
+	}
+
+	test.derivedMemoryLimit = applyDerivedMemoryLimit()
+
+	// Optionally populate the garbage collector controller before the test, like an application needing
+	// accurate admission from its first request
+	if os.Getenv("WARMUP") == "true" {
+		log.Printf("ControllerReady before Warmup: %t", rtml.ControllerReady())
+		warmupStart := time.Now()
+		rtml.Warmup()
+		if !rtml.ControllerReady() {
+			failf("ControllerReady is false after Warmup")
+		}
+		log.Printf("✅ ControllerReady is true after Warmup (took %v)", time.Since(warmupStart))
+	}
+
+	// Optionally expose the rtml debug page while the test is running
+	if addr := os.Getenv("DEBUG_ADDR"); addr != "" {
+		startDebugServer(addr)
+	}
 	log.Printf("Initial memory stats:")
 
 	// Log initial memory stats
@@ -42,17 +129,58 @@ func main() {
 	log.Printf("  HeapIdle: %d MB", bytesToMB(initialMemStats.HeapIdle))
 	log.Printf("  HeapInuse: %d MB", bytesToMB(initialMemStats.HeapInuse))
 
-	// Run the sanity check test
+	// Run the selected test
 	startTime := time.Now()
-	runSanityCheckTest(test)
+	switch mode := getEnvOrDefault("TEST_MODE", testModeSanity); mode {
+	case testModeSanity:
+		runSanityCheckTest(test)
+	case testModeLayout:
+		runLayoutTest()
+	case testModeBoundary:
+		runBoundaryTest(test)
+	case testModeResident:
+		runResidentTest(test)
+	case testModeResize:
+		runResizeTest(time.Duration(getEnvAsIntOrDefault("RESIZE_WAIT_SEC", 10))*time.Second,
+			getEnvAsIntOrDefault("RESIZE_RESERVE_PERCENT", 10))
+	case testModeOffHeap:
+		runOffHeapTest(uint64(getEnvAsIntOrDefault("OFFHEAP_SIZE_MB", int(test.allocSizeMB))))
+	case testModeSoak:
+		runSoakTest(parseSoakTest())
+	case testModeBench:
+		runBenchmarks()
+	case testModeAdmission:
+		runAdmissionTest(test, os.Getenv("ADMISSION_IGNORE_SIGNAL") == "true")
+	case testModeConcurrency:
+		runConcurrencyTest(test,
+			max(getEnvAsIntOrDefault("CONCURRENCY_WORKERS", 16), 1),
+			time.Duration(getEnvAsIntOrDefault("CONCURRENCY_DURATION_SEC", 10))*time.Second)
+	default:
+		failf("unknown TEST_MODE %q", mode)
+	}
 	duration := time.Since(startTime)
 
+	reportStats()
+	reportRSS()
 	log.Printf("=== Test completed successfully in %v ===", duration)
+	reportPass()
+}
+
+func startDebugServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/rtml", rtml.DebugHandler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Debug server stopped: %v", err)
+		}
+	}()
+	log.Printf("Debug server listening on %s/debug/rtml", addr)
 }
 
 func forceMemoryCommit(chunks [][]byte) {
 	log.Println("Forcing physical memory commit by touching all allocated bytes...")
 	var totalChecksum uint64
+	progress := newProgressLogger()
 	for i, chunk := range chunks {
 		// Touch every byte to force page commit to RSS
 		var chunkChecksum uint64
@@ -70,30 +198,28 @@ func forceMemoryCommit(chunks [][]byte) {
 			chunkChecksum += uint64(chunk[j]) // Read every page again
 		}
 
-		// Try to lock this chunk in memory (mlock)
+		// Try to lock this chunk in memory (mlock, linux only)
 		if len(chunk) > 0 {
-			// Note: This might fail due to container restrictions, but worth trying
-			syscall.Mlock(chunk)
+			lockChunk(chunk)
 		}
 
-		if i%10 == 0 {
-			log.Printf("Touched chunk %d/%d", i+1, len(chunks))
-		}
+		progress.Printf("Touched chunk %d/%d", i+1, len(chunks))
 	}
 	// Use total checksum to prevent optimization
 	log.Printf("Total checksum: %d", totalChecksum)
 
-	// Try to read process memory stats from /proc/self/status
-	if data, err := os.ReadFile("/proc/self/status"); err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "VmRSS:") {
-				log.Printf("Process RSS from /proc/self/status: %s", line)
-			}
-			if strings.HasPrefix(line, "VmSize:") {
-				log.Printf("Process VmSize from /proc/self/status: %s", line)
-			}
+	// Read process memory stats from /proc/self/status (or the runtime, where there is no /proc)
+	logProcessMemory()
+
+	// Compare with what the kernel charges the container cgroup
+	if heapMapped, current, ratio, err := rtml.RSSDivergence(); err == nil {
+		log.Printf("Cgroup memory current: %d MB, heap mapped: %d MB (ratio %.2f)",
+			bytesToMB(current), bytesToMB(heapMapped), ratio)
+		if ratio > rtml.RSSDivergenceWarningRatio {
+			log.Printf("⚠️  Non-heap memory dominates the container usage, the heuristic may be too optimistic")
 		}
+	} else {
+		log.Printf("Cgroup memory current not available: %v", err)
 	}
 
 	log.Println("Memory commit complete")
@@ -102,6 +228,9 @@ func forceMemoryCommit(chunks [][]byte) {
 func runSanityCheckTest(test SanityTest) {
 	log.Println("Running sanity check test...")
 
+	stopSampling := startSampler(test.sampleInterval)
+	defer stopSampling()
+
 	// Get initial stats
 	initialStats := rtml.GetMemLimitRelatedStats()
 	log.Printf("Initial RTML stats:")
@@ -112,52 +241,18 @@ func runSanityCheckTest(test SanityTest) {
 	log.Printf("  TotalAlloc: %d MB", bytesToMB(initialStats.TotalAlloc))
 	log.Printf("  TotalFree: %d MB", bytesToMB(initialStats.TotalFree))
 
-	// Allocate memory gradually
-	allocSizeBytes := mbToBytes(test.allocSizeMB)
-	chunkSize := uint64(256 * 1024) // 256KB chunks for more frequent allocation
-	numChunks := allocSizeBytes / chunkSize
-	globalChunks = make([][]byte, 0, numChunks)
-
-	log.Printf("Allocating %d MB in %d KB chunks...", test.allocSizeMB, chunkSize/1024)
-
+	// Allocate memory using the configured pattern
 	allocationStart := time.Now()
-	for i := uint64(0); i < numChunks; i++ {
-		chunk := make([]byte, chunkSize)
-
-		// Force RSS by touching every page in the chunk
-		// This ensures the memory is actually committed to physical RAM
-		var checksum uint64
-		for j := 0; j < len(chunk); j++ {
-			chunk[j] = byte(i%256 + 1)
-			// Force memory barrier every 4KB to ensure page commit
-			if j%4096 == 0 {
-				checksum += uint64(chunk[j]) // Read back and use the value
-			}
-		}
-		// Use checksum to prevent optimization
-		if checksum == 0 {
-			log.Printf("Warning: checksum is zero for chunk %d", i)
-		}
-
-		// Force a second pass to ensure pages are committed
-		for j := 0; j < len(chunk); j += 4096 {
-			checksum += uint64(chunk[j]) // Read every page again
-		}
-
-		globalChunks = append(globalChunks, chunk)
-
-		// Log progress every 10 chunks
-		if i%10 == 0 {
-			stats := rtml.GetMemLimitRelatedStats()
-			log.Printf("Progress: chunk %d/%d, HeapLive=%d MB, MappedReady=%d MB",
-				i+1, allocSizeBytes/chunkSize,
-				bytesToMB(stats.HeapLive),
-				bytesToMB(stats.MappedReady))
-		}
-	}
+	counter := rtml.NewCounter()
+	allocating.Store(true)
+	allocated := runAllocPattern(test)
+	allocating.Store(false)
+	windowAllocated, windowFreed := counter.Since()
 
 	allocationDuration := time.Since(allocationStart)
-	log.Printf("Successfully allocated %d MB in %v", test.allocSizeMB, allocationDuration)
+	log.Printf("Successfully allocated %d MB with the %s pattern in %v (total allocated %d MB, freed %d MB)",
+		test.allocSizeMB, test.allocPattern, allocationDuration,
+		bytesToMB(allocated.totalAllocated), bytesToMB(allocated.freed))
 
 	// Keep the chunks alive by doing some work with them
 	totalBytes := 0
@@ -200,143 +295,144 @@ func runSanityCheckTest(test SanityTest) {
 	log.Printf("  HeapGoal: %d MB", bytesToMB(finalStats.HeapGoal))
 	log.Printf("  HeapLive: %d MB", bytesToMB(finalStats.HeapLive))
 	log.Printf("  MappedReady: %d MB", bytesToMB(finalStats.MappedReady))
+	log.Printf("  HeapReleased: %d MB", bytesToMB(finalStats.HeapReleased))
+	log.Printf("  HeapInUse: %d MB (fragmentation %.2f)", bytesToMB(finalStats.HeapInUse), finalStats.Fragmentation())
 	log.Printf("  TotalAlloc: %d MB", bytesToMB(finalStats.TotalAlloc))
 	log.Printf("  TotalFree: %d MB", bytesToMB(finalStats.TotalFree))
+	log.Printf("  NetAllocated: %d MB", bytesToMB(finalStats.NetAllocatedBytes()))
+	log.Printf("  HeapGoalRatio: %.2f (distance to the next GC)", rtml.HeapGoalRatio())
+	log.Printf("  LimitRatio: %.2f (distance to the memory limit)", rtml.LimitRatio())
 
 	// Sanity checks with detailed error messages
 	log.Println("Performing sanity checks...")
 
 	// Check that MemoryLimit is not zero
 	if finalStats.MemoryLimit == 0 {
-		log.Printf("❌ FAIL: MemoryLimit is zero - RTML is not properly detecting memory limits")
-		os.Exit(1)
+		failf("MemoryLimit is zero - RTML is not properly detecting memory limits")
 	}
 	log.Printf("✅ MemoryLimit is valid: %d MB", bytesToMB(finalStats.MemoryLimit))
 
+	// Without GOMEMLIMIT the runtime reports math.MaxInt64, unless the limit was derived from the cgroup
+	checkMemoryLimitSource(finalStats.MemoryLimit, test.derivedMemoryLimit)
+
 	// Check that HeapGoal is not zero
 	if finalStats.HeapGoal == 0 {
-		log.Printf("❌ FAIL: HeapGoal is zero - RTML is not calculating heap goals properly")
-		os.Exit(1)
+		failf("HeapGoal is zero - RTML is not calculating heap goals properly")
 	}
 	log.Printf("✅ HeapGoal is valid: %d MB", bytesToMB(finalStats.HeapGoal))
 
+	// The learned overshoot is a part of a live heap observed above its goal, it can't exceed everything allocated
+	if overshoot := rtml.GCOvershootBytes(); overshoot > finalStats.TotalAlloc {
+		failCheck("GCOvershootBytes is larger than everything allocated", "GCOvershootBytes",
+			fmt.Sprintf("at most %d MB", bytesToMB(finalStats.TotalAlloc)), fmt.Sprintf("%d MB", bytesToMB(overshoot)))
+	} else {
+		log.Printf("✅ GCOvershootBytes learned an overshoot of %d KB", overshoot/1024)
+	}
+
+	// The allocation window must account for at least what the pattern allocated
+	if windowAllocated < allocated.totalAllocated*test.thresholds.totalAllocMinPercent/100 {
+		failf("Counter measured %d MB allocated in the allocation window, expected at least %d MB",
+			bytesToMB(windowAllocated), bytesToMB(allocated.totalAllocated*test.thresholds.totalAllocMinPercent/100))
+	}
+	log.Printf("✅ Counter measured %d MB allocated and %d MB freed in the allocation window",
+		bytesToMB(windowAllocated), bytesToMB(windowFreed))
+
 	// Check that HeapLive increased after allocation
 	if finalStats.HeapLive <= initialStats.HeapLive {
-		log.Printf("❌ FAIL: HeapLive did not increase after allocation")
-		log.Printf("   Initial: %d MB", bytesToMB(initialStats.HeapLive))
-		log.Printf("   Final: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("HeapLive did not increase after allocation", "HeapLive",
+			fmt.Sprintf("more than %d MB", bytesToMB(initialStats.HeapLive)), fmt.Sprintf("%d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	log.Printf("✅ HeapLive increased: %d MB -> %d MB",
 		bytesToMB(initialStats.HeapLive), bytesToMB(finalStats.HeapLive))
 
 	// Check that MappedReady is not zero
 	if finalStats.MappedReady == 0 {
-		log.Printf("❌ FAIL: MappedReady is zero - No memory pages are mapped and ready")
-		os.Exit(1)
+		failf("MappedReady is zero - No memory pages are mapped and ready")
 	}
 	log.Printf("✅ MappedReady is valid: %d MB", bytesToMB(finalStats.MappedReady))
 
 	// Check that TotalAlloc increased
 	if finalStats.TotalAlloc <= initialStats.TotalAlloc {
-		log.Printf("❌ FAIL: TotalAlloc did not increase")
-		log.Printf("   Initial: %d MB", bytesToMB(initialStats.TotalAlloc))
-		log.Printf("   Final: %d MB", bytesToMB(finalStats.TotalAlloc))
-		os.Exit(1)
+		failCheck("TotalAlloc did not increase", "TotalAlloc",
+			fmt.Sprintf("more than %d MB", bytesToMB(initialStats.TotalAlloc)), fmt.Sprintf("%d MB", bytesToMB(finalStats.TotalAlloc)))
 	}
 	log.Printf("✅ TotalAlloc increased: %d MB -> %d MB",
 		bytesToMB(initialStats.TotalAlloc), bytesToMB(finalStats.TotalAlloc))
 
-	// Check that HeapLive is reasonable (should be between 90% and 120% of allocated memory)
-	expectedMinHeapLive := mbToBytes(test.allocSizeMB) * 9 / 10  // 90% of allocated
-	expectedMaxHeapLive := mbToBytes(test.allocSizeMB) * 12 / 10 // 120% of allocated
+	thresholds := test.thresholds
+
+	// Check that HeapLive is reasonable (by default between 90% and 120% of allocated memory)
+	expectedMinHeapLive := mbToBytes(test.allocSizeMB) * thresholds.heapLiveMinPercent / 100
+	expectedMaxHeapLive := mbToBytes(test.allocSizeMB) * thresholds.heapLiveMaxPercent / 100
 	if finalStats.HeapLive < expectedMinHeapLive {
-		log.Printf("❌ FAIL: HeapLive too low")
-		log.Printf("   Expected at least: %d MB", bytesToMB(expectedMinHeapLive))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("HeapLive too low", "HeapLive",
+			fmt.Sprintf("at least %d MB", bytesToMB(expectedMinHeapLive)), fmt.Sprintf("%d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	if finalStats.HeapLive > expectedMaxHeapLive {
-		log.Printf("❌ FAIL: HeapLive too high")
-		log.Printf("   Expected at most: %d MB", bytesToMB(expectedMaxHeapLive))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("HeapLive too high", "HeapLive",
+			fmt.Sprintf("at most %d MB", bytesToMB(expectedMaxHeapLive)), fmt.Sprintf("%d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	log.Printf("✅ HeapLive is reasonable: %d MB (allocated %d MB, expected %d-%d MB)",
 		bytesToMB(finalStats.HeapLive), test.allocSizeMB,
 		bytesToMB(expectedMinHeapLive), bytesToMB(expectedMaxHeapLive))
 
-	// Check that MappedReady is reasonable (should be between HeapLive + 2MB and HeapLive + 10MB)
-	expectedMinMappedReady := finalStats.HeapLive + mbToBytes(2)  // HeapLive + 2MB overhead
-	expectedMaxMappedReady := finalStats.HeapLive + mbToBytes(10) // HeapLive + 10MB max overhead
+	// Check that MappedReady is reasonable (by default between HeapLive + 2MB and HeapLive + 10MB)
+	expectedMinMappedReady := finalStats.HeapLive + mbToBytes(thresholds.mappedReadyMinOverheadMB)
+	expectedMaxMappedReady := finalStats.HeapLive + mbToBytes(thresholds.mappedReadyMaxOverheadMB)
 	if finalStats.MappedReady < expectedMinMappedReady {
-		log.Printf("❌ FAIL: MappedReady too low")
-		log.Printf("   Expected at least: %d MB", bytesToMB(expectedMinMappedReady))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.MappedReady))
-		log.Printf("   HeapLive: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("MappedReady too low", "MappedReady",
+			fmt.Sprintf("at least %d MB", bytesToMB(expectedMinMappedReady)), fmt.Sprintf("%d MB", bytesToMB(finalStats.MappedReady)),
+			fmt.Sprintf("HeapLive: %d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	if finalStats.MappedReady > expectedMaxMappedReady {
-		log.Printf("❌ FAIL: MappedReady too high")
-		log.Printf("   Expected at most: %d MB", bytesToMB(expectedMaxMappedReady))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.MappedReady))
-		log.Printf("   HeapLive: %d MB", bytesToMB(finalStats.HeapLive))
-		log.Printf("   This could indicate:")
-		log.Printf("   - Excessive memory mapping overhead")
-		log.Printf("   - Memory fragmentation")
-		os.Exit(1)
+		failCheck("MappedReady too high", "MappedReady",
+			fmt.Sprintf("at most %d MB", bytesToMB(expectedMaxMappedReady)), fmt.Sprintf("%d MB", bytesToMB(finalStats.MappedReady)),
+			fmt.Sprintf("HeapLive: %d MB", bytesToMB(finalStats.HeapLive)),
+			"This could indicate:",
+			"- Excessive memory mapping overhead",
+			"- Memory fragmentation")
 	}
 	log.Printf("✅ MappedReady is reasonable: %d MB (HeapLive: %d MB, expected %d-%d MB)",
 		bytesToMB(finalStats.MappedReady), bytesToMB(finalStats.HeapLive),
 		bytesToMB(expectedMinMappedReady), bytesToMB(expectedMaxMappedReady))
 
-	// Check that HeapGoal is reasonable (should be between HeapLive and HeapLive + 60MB)
-	expectedMinHeapGoal := finalStats.HeapLive                 // HeapGoal should be at least HeapLive
-	expectedMaxHeapGoal := finalStats.HeapLive + mbToBytes(60) // HeapLive + 60MB max growth allowance
+	// Check that HeapGoal is reasonable (by default between HeapLive and HeapLive + 60MB)
+	expectedMinHeapGoal := finalStats.HeapLive // HeapGoal should be at least HeapLive
+	expectedMaxHeapGoal := finalStats.HeapLive + mbToBytes(thresholds.heapGoalMaxGrowthMB)
 	if finalStats.HeapGoal < expectedMinHeapGoal {
-		log.Printf("❌ FAIL: HeapGoal too low")
-		log.Printf("   Expected at least: %d MB", bytesToMB(expectedMinHeapGoal))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.HeapGoal))
-		log.Printf("   HeapLive: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("HeapGoal too low", "HeapGoal",
+			fmt.Sprintf("at least %d MB", bytesToMB(expectedMinHeapGoal)), fmt.Sprintf("%d MB", bytesToMB(finalStats.HeapGoal)),
+			fmt.Sprintf("HeapLive: %d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	if finalStats.HeapGoal > expectedMaxHeapGoal {
-		log.Printf("❌ FAIL: HeapGoal too high")
-		log.Printf("   Expected at most: %d MB", bytesToMB(expectedMaxHeapGoal))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.HeapGoal))
-		log.Printf("   HeapLive: %d MB", bytesToMB(finalStats.HeapLive))
-		os.Exit(1)
+		failCheck("HeapGoal too high", "HeapGoal",
+			fmt.Sprintf("at most %d MB", bytesToMB(expectedMaxHeapGoal)), fmt.Sprintf("%d MB", bytesToMB(finalStats.HeapGoal)),
+			fmt.Sprintf("HeapLive: %d MB", bytesToMB(finalStats.HeapLive)))
 	}
 	log.Printf("✅ HeapGoal is reasonable: %d MB (HeapLive: %d MB, expected %d-%d MB)",
 		bytesToMB(finalStats.HeapGoal), bytesToMB(finalStats.HeapLive),
 		bytesToMB(expectedMinHeapGoal), bytesToMB(expectedMaxHeapGoal))
 
-	// Check that TotalAlloc is reasonable (should be between 90% and 120% of allocated amount)
-	expectedMinTotalAlloc := mbToBytes(test.allocSizeMB) * 9 / 10  // 90% of allocated
-	expectedMaxTotalAlloc := mbToBytes(test.allocSizeMB) * 12 / 10 // 120% of allocated
+	// Check that TotalAlloc is reasonable (by default between 90% and 120% of the total allocated by the pattern)
+	expectedMinTotalAlloc := allocated.totalAllocated * thresholds.totalAllocMinPercent / 100
+	expectedMaxTotalAlloc := allocated.totalAllocated * thresholds.totalAllocMaxPercent / 100
 	if finalStats.TotalAlloc < expectedMinTotalAlloc {
-		log.Printf("❌ FAIL: TotalAlloc too low")
-		log.Printf("   Expected at least: %d MB", bytesToMB(expectedMinTotalAlloc))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.TotalAlloc))
-		os.Exit(1)
+		failCheck("TotalAlloc too low", "TotalAlloc",
+			fmt.Sprintf("at least %d MB", bytesToMB(expectedMinTotalAlloc)), fmt.Sprintf("%d MB", bytesToMB(finalStats.TotalAlloc)))
 	}
 	if finalStats.TotalAlloc > expectedMaxTotalAlloc {
-		log.Printf("❌ FAIL: TotalAlloc too high")
-		log.Printf("   Expected at most: %d MB", bytesToMB(expectedMaxTotalAlloc))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.TotalAlloc))
-		os.Exit(1)
+		failCheck("TotalAlloc too high", "TotalAlloc",
+			fmt.Sprintf("at most %d MB", bytesToMB(expectedMaxTotalAlloc)), fmt.Sprintf("%d MB", bytesToMB(finalStats.TotalAlloc)))
 	}
 	log.Printf("✅ TotalAlloc is reasonable: %d MB (allocated %d MB, expected %d-%d MB)",
-		bytesToMB(finalStats.TotalAlloc), test.allocSizeMB,
+		bytesToMB(finalStats.TotalAlloc), bytesToMB(allocated.totalAllocated),
 		bytesToMB(expectedMinTotalAlloc), bytesToMB(expectedMaxTotalAlloc))
 
-	// Check that TotalFree is reasonable (should be 0 or very small for our test)
-	expectedMaxTotalFree := mbToBytes(5) // 5MB max
+	// Check that TotalFree is reasonable (should be 0 or very small, beyond what the pattern freed)
+	expectedMaxTotalFree := allocated.freed + mbToBytes(thresholds.totalFreeMaxMB) // 5MB max by default
 	if finalStats.TotalFree > expectedMaxTotalFree {
-		log.Printf("❌ FAIL: TotalFree too high")
-		log.Printf("   Expected at most: %d MB", bytesToMB(expectedMaxTotalFree))
-		log.Printf("   Got: %d MB", bytesToMB(finalStats.TotalFree))
-		os.Exit(1)
+		failCheck("TotalFree too high", "TotalFree",
+			fmt.Sprintf("at most %d MB", bytesToMB(expectedMaxTotalFree)), fmt.Sprintf("%d MB", bytesToMB(finalStats.TotalFree)))
 	}
 	log.Printf("✅ TotalFree is reasonable: %d MB", bytesToMB(finalStats.TotalFree))
 
@@ -344,6 +440,13 @@ func runSanityCheckTest(test SanityTest) {
 	log.Println("Sanity check test completed successfully")
 }
 
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsIntOrDefault(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
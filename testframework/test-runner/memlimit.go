@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// derivedMemoryLimit describes how the runner derived the memory limit from the cgroup limit on startup
+type derivedMemoryLimit struct {
+	description string
+	fromCgroup  func(cgroupLimit uint64) uint64 // the expected limit
+}
+
+// applyDerivedMemoryLimit derives the memory limit from the container (cgroup) limit on startup, like an application would:
+// with rtml.SetMemoryLimitPercent when MEMLIMIT_PERCENT is set, or rtml.AutoSetMemoryLimitFromCgroup when
+// AUTO_MEMLIMIT_RESERVE is set. It returns nil when neither is set, and the limit comes from GOMEMLIMIT.
+func applyDerivedMemoryLimit() *derivedMemoryLimit {
+	if value := os.Getenv("MEMLIMIT_PERCENT"); value != "" {
+		percent, err := strconv.Atoi(value)
+		if err != nil {
+			failf("invalid MEMLIMIT_PERCENT %q: %v", value, err)
+		}
+		limit, err := rtml.SetMemoryLimitPercent(percent)
+		if err != nil {
+			failf("failed to set the memory limit to %d%% of the cgroup limit: %v", percent, err)
+		}
+		log.Printf("Memory limit set to %d%% of the cgroup limit: %d MB", percent, bytesToMB(uint64(limit)))
+		return &derivedMemoryLimit{
+			description: fmt.Sprintf("%d%% of the cgroup limit", percent),
+			fromCgroup: func(cgroupLimit uint64) uint64 {
+				return cgroupLimit/100*uint64(percent) + cgroupLimit%100*uint64(percent)/100
+			},
+		}
+	}
+
+	if value := os.Getenv("AUTO_MEMLIMIT_RESERVE"); value != "" {
+		reserve, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			failf("invalid AUTO_MEMLIMIT_RESERVE %q: %v", value, err)
+		}
+		limit, err := rtml.AutoSetMemoryLimitFromCgroup(reserve)
+		if err != nil {
+			failf("failed to derive the memory limit from the cgroup: %v", err)
+		}
+		log.Printf("Memory limit derived from the cgroup: %d MB (reserve %.0f%%)", bytesToMB(uint64(limit)), reserve*100)
+		return &derivedMemoryLimit{
+			description: fmt.Sprintf("cgroup limit with %.0f%% reserve", reserve*100),
+			fromCgroup: func(cgroupLimit uint64) uint64 {
+				return uint64(float64(cgroupLimit) * (1 - reserve))
+			},
+		}
+	}
+
+	return nil
+}
+
+// checkMemoryLimitSource verifies the memory limit the runtime reports was actually configured.
+//
+// Without GOMEMLIMIT the runtime reports the math.MaxInt64 sentinel, not 0, and the stats checks that follow
+// (HeapGoal near HeapLive, IsMemLimitReached) pass while being meaningless. The sentinel is only accepted when
+// the test opts out explicitly with GOMEMLIMIT=off. Any limit in effect, including an explicit GOMEMLIMIT, must not
+// exceed the container (cgroup) limit, and a limit derived from the cgroup must match it exactly.
+func checkMemoryLimitSource(memoryLimit uint64, derived *derivedMemoryLimit) {
+	if memoryLimit == math.MaxInt64 {
+		if os.Getenv("GOMEMLIMIT") != "off" {
+			failCheck("no memory limit is set, the runtime reports the math.MaxInt64 sentinel", "MemoryLimit",
+				"a memory limit", fmt.Sprintf("%d", memoryLimit),
+				"GOMEMLIMIT is not set and was not derived from the cgroup (MEMLIMIT_PERCENT, AUTO_MEMLIMIT_RESERVE),",
+				"so IsMemLimitReached can never return true. Use GOMEMLIMIT=off to test this on purpose")
+		}
+		log.Printf("✅ No memory limit is set, as requested with GOMEMLIMIT=off")
+		return
+	}
+
+	cgroupLimit, err := rtml.CgroupMemoryMax()
+	if err != nil {
+		if derived != nil {
+			failf("the memory limit was derived from the cgroup, but the cgroup limit can't be read: %v", err)
+		}
+		// e.g. running outside of a container, there is no container limit to compare with
+		log.Printf("⚠️ The cgroup limit can't be read, MemoryLimit %d MB is not compared with it: %v", bytesToMB(memoryLimit), err)
+		return
+	}
+	if memoryLimit > cgroupLimit {
+		failCheck("MemoryLimit is above the container limit, the container is OOM killed before the limit is reached", "MemoryLimit",
+			fmt.Sprintf("at most the cgroup limit %d", cgroupLimit), fmt.Sprintf("%d", memoryLimit))
+	}
+	if derived == nil {
+		log.Printf("✅ MemoryLimit %d MB is within the cgroup limit %d MB", bytesToMB(memoryLimit), bytesToMB(cgroupLimit))
+		return
+	}
+	if expected := derived.fromCgroup(cgroupLimit); memoryLimit != expected {
+		failCheck("MemoryLimit is not derived from the cgroup limit", "MemoryLimit",
+			fmt.Sprintf("%d (%s, %d MB)", expected, derived.description, bytesToMB(cgroupLimit)), fmt.Sprintf("%d", memoryLimit))
+	}
+	log.Printf("✅ MemoryLimit is %s (%d MB)", derived.description, bytesToMB(cgroupLimit))
+}
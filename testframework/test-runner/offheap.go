@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+const offHeapChunkSize = 8 * 1024 * 1024
+
+// Keeps the off-heap mappings alive
+var offHeapChunks [][]byte
+
+// runOffHeapTest maps sizeMB of memory outside of the go heap (mmap), like cgo or memory mapped files would,
+// touching every page so it is charged to the container.
+//
+// rtml only sees the memory managed by the go runtime, so IsMemLimitReached is expected to stay false
+// however much is mapped: with more off-heap memory than the container limit, the container is OOM killed
+// while rtml reports "not reached". The runner prints "RTML_REACHED=<bool>" after every chunk,
+// so the framework can flag the OOM kill with the last result rtml reported.
+func runOffHeapTest(sizeMB uint64) {
+	log.Printf("Running off-heap test (%d MB)...", sizeMB)
+
+	for allocated := uint64(0); allocated < mbToBytes(sizeMB); allocated += offHeapChunkSize {
+		chunk, err := mapOffHeap(offHeapChunkSize)
+		if err != nil {
+			failf("failed to map off-heap memory: %v", err)
+		}
+		for i := 0; i < len(chunk); i += 4096 {
+			chunk[i] = 1
+		}
+		offHeapChunks = append(offHeapChunks, chunk)
+
+		reached := rtml.IsMemLimitReached()
+		fmt.Printf("%s=%t\n", reachedMarker, reached)
+		if reached {
+			failf("IsMemLimitReached returned true after %d MB of off-heap memory, which rtml does not account for",
+				bytesToMB(allocated+offHeapChunkSize))
+		}
+
+		if len(offHeapChunks)%8 == 0 {
+			if heapMapped, current, ratio, err := rtml.RSSDivergence(); err == nil {
+				log.Printf("Off-heap %d MB: cgroup memory current %d MB, heap mapped %d MB (ratio %.2f)",
+					bytesToMB(allocated+offHeapChunkSize), bytesToMB(current), bytesToMB(heapMapped), ratio)
+			}
+		}
+	}
+
+	log.Printf("✅ IsMemLimitReached stayed false with %d MB of off-heap memory, which rtml does not account for", sizeMB)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// mapOffHeap is only implemented on linux, where the off-heap test runs in a container
+func mapOffHeap(size int) ([]byte, error) {
+	return nil, fmt.Errorf("off-heap allocations are not supported on %s", runtime.GOOS)
+}
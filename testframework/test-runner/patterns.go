@@ -0,0 +1,185 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// Allocation patterns selected with the ALLOC_PATTERN environment variable.
+// Every pattern ends with globalChunks holding ALLOC_SIZE_MB of live memory,
+// so the same sanity checks apply, but they stress the heuristic differently on the way.
+const (
+	// allocate gradually in small chunks (the default)
+	allocPatternLinear = "linear"
+
+	// allocate as fast as possible in large chunks, then hold the memory
+	allocPatternSpike = "spike"
+
+	// allocate the whole size, then free it all, repeatedly
+	allocPatternSawtooth = "sawtooth"
+
+	// keep a constant live set while replacing chunks at a high rate
+	allocPatternChurn = "churn"
+)
+
+const (
+	linearChunkSize = 256 * 1024 // 256KB chunks for more frequent allocation
+	spikeChunkSize  = 4 * 1024 * 1024
+	spikeHoldTime   = time.Second
+)
+
+// allocResult describes how much the pattern allocated and freed in total,
+// which the TotalAlloc and TotalFree checks take into account.
+type allocResult struct {
+	totalAllocated uint64
+	freed          uint64
+}
+
+func isValidAllocPattern(pattern string) bool {
+	switch pattern {
+	case allocPatternLinear, allocPatternSpike, allocPatternSawtooth, allocPatternChurn:
+		return true
+	}
+	return false
+}
+
+// runAllocPattern allocates test.allocSizeMB of live memory into globalChunks using the test pattern
+func runAllocPattern(test SanityTest) allocResult {
+	allocSizeBytes := mbToBytes(test.allocSizeMB)
+
+	switch test.allocPattern {
+	case allocPatternSpike:
+		return allocateSpike(allocSizeBytes)
+	case allocPatternSawtooth:
+		return allocateSawtooth(allocSizeBytes, test.allocCycles)
+	case allocPatternChurn:
+		return allocateChurn(allocSizeBytes, test.allocCycles)
+	default:
+		return allocateLinear(allocSizeBytes)
+	}
+}
+
+// allocateChunk allocates a chunk and touches every page in it,
+// to ensure the memory is actually committed to physical RAM
+func allocateChunk(index uint64, size uint64) []byte {
+	chunk := make([]byte, size)
+
+	var checksum uint64
+	for j := 0; j < len(chunk); j++ {
+		chunk[j] = byte(index%256 + 1)
+		// Force memory barrier every 4KB to ensure page commit
+		if j%4096 == 0 {
+			checksum += uint64(chunk[j]) // Read back and use the value
+		}
+	}
+	// Use checksum to prevent optimization
+	if checksum == 0 {
+		log.Printf("Warning: checksum is zero for chunk %d", index)
+	}
+
+	// Force a second pass to ensure pages are committed
+	for j := 0; j < len(chunk); j += 4096 {
+		checksum += uint64(chunk[j]) // Read every page again
+	}
+
+	return chunk
+}
+
+// fillChunks appends chunks to globalChunks until it holds sizeBytes,
+// logging the progress at most every PROGRESS_LOG_INTERVAL_MS when logProgress is set
+func fillChunks(sizeBytes uint64, chunkSize uint64, logProgress bool) {
+	numChunks := sizeBytes / chunkSize
+	globalChunks = make([][]byte, 0, numChunks)
+
+	progress := newProgressLogger()
+	for i := uint64(0); i < numChunks; i++ {
+		globalChunks = append(globalChunks, allocateChunk(i, chunkSize))
+
+		if logProgress && progress.Allow() {
+			stats := rtml.GetMemLimitRelatedStats()
+			log.Printf("Progress: chunk %d/%d, HeapLive=%d MB, MappedReady=%d MB",
+				i+1, numChunks,
+				bytesToMB(stats.HeapLive),
+				bytesToMB(stats.MappedReady))
+		}
+	}
+}
+
+func allocateLinear(sizeBytes uint64) allocResult {
+	log.Printf("Allocating %d MB in %d KB chunks...", bytesToMB(sizeBytes), linearChunkSize/1024)
+	fillChunks(sizeBytes, linearChunkSize, true)
+	return allocResult{totalAllocated: sizeBytes}
+}
+
+func allocateSpike(sizeBytes uint64) allocResult {
+	log.Printf("Allocating %d MB at once in %d MB chunks...", bytesToMB(sizeBytes), spikeChunkSize/(1024*1024))
+	fillChunks(sizeBytes, spikeChunkSize, false)
+
+	stats := rtml.GetMemLimitRelatedStats()
+	log.Printf("Spike allocated, holding for %v: HeapLive=%d MB, MappedReady=%d MB, IsMemLimitReached=%t",
+		spikeHoldTime, bytesToMB(stats.HeapLive), bytesToMB(stats.MappedReady), rtml.IsMemLimitReached())
+	time.Sleep(spikeHoldTime)
+
+	return allocResult{totalAllocated: sizeBytes}
+}
+
+func allocateSawtooth(sizeBytes uint64, cycles int) allocResult {
+	log.Printf("Allocating and freeing %d MB for %d cycles...", bytesToMB(sizeBytes), cycles)
+
+	var result allocResult
+	for cycle := 1; cycle <= cycles; cycle++ {
+		fillChunks(sizeBytes, linearChunkSize, false)
+		result.totalAllocated += sizeBytes
+
+		stats := rtml.GetMemLimitRelatedStats()
+		log.Printf("Sawtooth cycle %d/%d peak: HeapLive=%d MB, MappedReady=%d MB, IsMemLimitReached=%t",
+			cycle, cycles, bytesToMB(stats.HeapLive), bytesToMB(stats.MappedReady), rtml.IsMemLimitReached())
+
+		// The last cycle keeps its memory for the sanity checks
+		if cycle == cycles {
+			break
+		}
+		globalChunks = nil
+		result.freed += sizeBytes
+		settleHeap()
+	}
+
+	return result
+}
+
+func allocateChurn(sizeBytes uint64, rounds int) allocResult {
+	log.Printf("Allocating %d MB and replacing it %d times over...", bytesToMB(sizeBytes), rounds)
+	fillChunks(sizeBytes, linearChunkSize, false)
+	result := allocResult{totalAllocated: sizeBytes}
+	if len(globalChunks) == 0 {
+		return result
+	}
+
+	// Replace chunks one at a time, so the live set stays constant while the allocation rate is high
+	replacements := uint64(rounds) * uint64(len(globalChunks))
+	for i := uint64(0); i < replacements; i++ {
+		index := i % uint64(len(globalChunks))
+		globalChunks[index] = allocateChunk(i, linearChunkSize)
+		result.totalAllocated += linearChunkSize
+		result.freed += linearChunkSize
+
+		if i%uint64(len(globalChunks)) == 0 {
+			stats := rtml.GetMemLimitRelatedStats()
+			log.Printf("Churn progress: replaced %d/%d chunks, HeapLive=%d MB, MappedReady=%d MB, IsMemLimitReached=%t",
+				i, replacements, bytesToMB(stats.HeapLive), bytesToMB(stats.MappedReady), rtml.IsMemLimitReached())
+		}
+	}
+
+	// Drop the garbage, so the final checks see the live set rather than the churn leftovers
+	settleHeap()
+	return result
+}
+
+// settleHeap collects the garbage and returns the freed memory to the OS,
+// so that MappedReady reflects the live set again
+func settleHeap() {
+	debug.FreeOSMemory()
+}
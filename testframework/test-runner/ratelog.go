@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// minimum time between progress logs in allocation loops (PROGRESS_LOG_INTERVAL_MS)
+var progressLogInterval = 250 * time.Millisecond
+
+// rateLimitedLogger logs at most once per interval, however many iterations call it.
+// Logging and reading the stats for it in a tight allocation loop perturbs the timing and memory being measured,
+// and on large ALLOC_SIZE_MB runs a log every few chunks floods the output.
+// It is not safe for concurrent use, create one per loop.
+type rateLimitedLogger struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func newProgressLogger() *rateLimitedLogger {
+	return &rateLimitedLogger{interval: progressLogInterval}
+}
+
+// Allow reports whether a line can be logged now, and if so counts it as logged.
+// Check it before computing expensive log arguments (e.g. reading the stats).
+func (l *rateLimitedLogger) Allow() bool {
+	now := time.Now()
+	if !l.last.IsZero() && now.Sub(l.last) < l.interval {
+		return false
+	}
+	l.last = now
+	return true
+}
+
+// Printf logs like log.Printf when allowed, and drops the line otherwise.
+func (l *rateLimitedLogger) Printf(format string, args ...any) {
+	if l.Allow() {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime/debug"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// The resident test prints
+// "RTML_RESIDENT memoryCurrent=<bytes> inUse=<bytes> inUseMinusReleased=<bytes> heapReleased=<bytes>"
+const residentMarker = "RTML_RESIDENT"
+
+// runResidentTest compares the figures rtml could count as resident memory with what the kernel charges the container:
+// MappedReady - HeapFree (what IsMemLimitReached uses), and the same minus HeapReleased.
+//
+// It allocates test.allocSizeMB, drops half of it and returns the free memory to the OS, so HeapReleased is large.
+// The runtime already removes released memory from MappedReady, so the test fails if the figure
+// without HeapReleased is closer to memory.current than MappedReady - HeapFree.
+func runResidentTest(test SanityTest) {
+	log.Println("Running resident memory figure test...")
+
+	fillChunks(mbToBytes(test.allocSizeMB), linearChunkSize, false)
+	kept := len(globalChunks) / 2
+	clear(globalChunks[kept:])
+	globalChunks = globalChunks[:kept]
+	debug.FreeOSMemory()
+
+	stats := rtml.GetMemLimitRelatedStats()
+	current, err := rtml.CgroupMemoryCurrent()
+	if err != nil {
+		current, err = readProcStatusBytes("VmRSS")
+	}
+	if err != nil {
+		failf("the resident test requires cgroup memory.current or VmRSS: %v", err)
+	}
+
+	inUse := stats.MappedReady - min(stats.HeapFree, stats.MappedReady)
+	inUseMinusReleased := inUse - min(stats.HeapReleased, inUse)
+	fmt.Printf("%s memoryCurrent=%d inUse=%d inUseMinusReleased=%d heapReleased=%d\n",
+		residentMarker, current, inUse, inUseMinusReleased, stats.HeapReleased)
+	log.Printf("memory.current %d MB, MappedReady - HeapFree %d MB, minus HeapReleased %d MB (HeapReleased %d MB)",
+		bytesToMB(current), bytesToMB(inUse), bytesToMB(inUseMinusReleased), bytesToMB(stats.HeapReleased))
+
+	// Without released memory the two figures are the same, and the comparison says nothing
+	if minReleased := mbToBytes(test.allocSizeMB) / 4; stats.HeapReleased < minReleased {
+		failCheck("the runtime did not release the dropped memory", "HeapReleased",
+			fmt.Sprintf("at least %d MB", bytesToMB(minReleased)), fmt.Sprintf("%d MB", bytesToMB(stats.HeapReleased)))
+	}
+
+	inUseError := math.Abs(float64(current) - float64(inUse))
+	minusReleasedError := math.Abs(float64(current) - float64(inUseMinusReleased))
+	if minusReleasedError < inUseError {
+		failCheck("MappedReady - HeapFree - HeapReleased is closer to the resident memory than MappedReady - HeapFree", "inUse",
+			fmt.Sprintf("within %d MB of memory.current %d MB", bytesToMB(uint64(minusReleasedError)), bytesToMB(current)),
+			fmt.Sprintf("%d MB", bytesToMB(inUse)))
+	}
+	log.Printf("✅ MappedReady - HeapFree is off by %d MB from the resident memory, subtracting HeapReleased again is off by %d MB",
+		bytesToMB(uint64(inUseError)), bytesToMB(uint64(minusReleasedError)))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// How often the resize test samples, and re-reads the cgroup limit
+const (
+	resizeSampleInterval = 100 * time.Millisecond
+	resizeCheckInterval  = 200 * time.Millisecond
+)
+
+// cgroupLimitChange is a change reported by WithCgroupLimitTracking
+type cgroupLimitChange struct {
+	oldCgroupLimit, newCgroupLimit uint64
+	memoryLimit                    int64
+}
+
+// runResizeTest derives the memory limit from the cgroup limit, then waits for the framework to resize
+// the container (docker update, like a kubernetes in-place pod resize), and checks a Monitor with
+// WithCgroupLimitTracking moved the memory limit to the new cgroup limit minus the reserve.
+func runResizeTest(wait time.Duration, reservePercent int) {
+	log.Printf("Running cgroup limit resize test, waiting up to %v for the container limit to change...", wait)
+	reserve := float64(reservePercent) / 100
+
+	initialLimit, err := rtml.AutoSetMemoryLimitFromCgroup(reserve)
+	if err != nil {
+		failf("the resize test requires a cgroup memory limit: %v", err)
+	}
+	log.Printf("Memory limit derived from the cgroup: %d MB (reserve %d%%)", bytesToMB(uint64(initialLimit)), reservePercent)
+
+	changes := make(chan cgroupLimitChange, 1)
+	monitor := rtml.NewMonitor(resizeSampleInterval, nil,
+		rtml.WithCgroupLimitTracking(resizeCheckInterval, reserve, func(oldCgroupLimit, newCgroupLimit uint64, memoryLimit int64) {
+			select {
+			case changes <- cgroupLimitChange{oldCgroupLimit, newCgroupLimit, memoryLimit}:
+			default:
+			}
+		}))
+	monitor.Start()
+	defer monitor.Stop()
+
+	var change cgroupLimitChange
+	select {
+	case change = <-changes:
+	case <-time.After(wait):
+		failf("the cgroup memory limit did not change within %v", wait)
+	}
+	log.Printf("Cgroup memory limit changed: %d MB -> %d MB, memory limit set to %d MB",
+		bytesToMB(change.oldCgroupLimit), bytesToMB(change.newCgroupLimit), bytesToMB(uint64(change.memoryLimit)))
+
+	expected := uint64(float64(change.newCgroupLimit) * (1 - reserve))
+	if memoryLimit := rtml.GetMemLimitRelatedStats().MemoryLimit; memoryLimit != expected || uint64(change.memoryLimit) != expected {
+		failCheck("MemoryLimit did not follow the new cgroup limit", "MemoryLimit",
+			fmt.Sprintf("%d (%d MB cgroup limit with %d%% reserve)", expected, bytesToMB(change.newCgroupLimit), reservePercent),
+			fmt.Sprintf("%d (reported %d)", memoryLimit, change.memoryLimit))
+	}
+	log.Printf("✅ MemoryLimit followed the cgroup limit to %d MB", bytesToMB(expected))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// resultMarker is the last line the runner prints, with the outcome the framework reads the failure details from:
+// "RTML_RESULT status=pass", or
+// "RTML_RESULT status=fail reason=<quoted> field=<quoted> expected=<quoted> actual=<quoted>".
+// Values are quoted with Go syntax, and field, expected and actual are only printed by the checks
+// comparing a value against an expectation.
+const resultMarker = "RTML_RESULT"
+
+// reportPass prints the passing RTML_RESULT line, once every check passed
+func reportPass() {
+	fmt.Printf("%s status=pass\n", resultMarker)
+}
+
+// failf fails the test with a reason, and exits
+func failf(format string, args ...any) {
+	failCheck(fmt.Sprintf(format, args...), "", "", "")
+}
+
+// failCheck fails the test on a check of field, logging the expected and actual values followed by the details lines,
+// prints the failing RTML_RESULT line and exits. field, expected and actual can be empty when they don't apply.
+func failCheck(reason, field, expected, actual string, details ...string) {
+	log.Printf("❌ FAIL: %s", reason)
+	if expected != "" {
+		log.Printf("   Expected: %s", expected)
+	}
+	if actual != "" {
+		log.Printf("   Got: %s", actual)
+	}
+	for _, detail := range details {
+		log.Printf("   %s", detail)
+	}
+
+	line := []string{resultMarker, "status=fail", fmt.Sprintf("reason=%q", reason)}
+	for _, kv := range [][2]string{{"field", field}, {"expected", expected}, {"actual", actual}} {
+		if kv[1] != "" {
+			line = append(line, fmt.Sprintf("%s=%q", kv[0], kv[1]))
+		}
+	}
+	fmt.Println(strings.Join(line, " "))
+	os.Exit(1)
+}
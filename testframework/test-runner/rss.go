@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// Structured lines parsed by the test framework, which are more reliable than the docker stats API
+const (
+	finalRSSMarker = "RTML_FINAL_RSS_BYTES"
+	peakRSSMarker  = "RTML_PEAK_RSS_BYTES"
+	statMarker     = "RTML_STAT"
+	reachedMarker  = "RTML_REACHED"
+	sampleMarker   = "RTML_SAMPLE"
+	benchMarker    = "RTML_BENCH"
+)
+
+// the time samples are reported relative to
+var processStart = time.Now()
+
+// set while the test allocates, so the framework can tell a frozen runner (allocating without progress)
+// from one that is just checking the results
+var allocating atomic.Bool
+
+// reportSample prints a point of the memory time series as
+// "RTML_SAMPLE t=<ms> heapLive=<bytes> mappedReady=<bytes> heapGoal=<bytes> totalAlloc=<bytes> allocating=<bool>",
+// which the test framework charts in the html report, and watches for freezes. t is the time since the runner started.
+// See startSampler for the periodic samples.
+func reportSample(stats rtml.MemLimitRelatedStats) {
+	fmt.Printf("%s t=%d heapLive=%d mappedReady=%d heapGoal=%d totalAlloc=%d allocating=%t\n",
+		sampleMarker, time.Since(processStart).Milliseconds(), stats.HeapLive, stats.MappedReady, stats.HeapGoal,
+		stats.TotalAlloc, allocating.Load())
+}
+
+// reportStats prints the final rtml stats as a single "RTML_STAT Field=value ..." line,
+// which the test framework evaluates against the assertions of the test config.
+func reportStats() {
+	stats := rtml.GetMemLimitRelatedStats()
+	fmt.Printf("%s MemoryLimit=%d HeapGoal=%d HeapLive=%d MappedReady=%d HeapFree=%d HeapReleased=%d HeapInUse=%d TotalAlloc=%d TotalFree=%d IsMemLimitReached=%t\n",
+		statMarker, stats.MemoryLimit, stats.HeapGoal, stats.HeapLive, stats.MappedReady,
+		stats.HeapFree, stats.HeapReleased, stats.HeapInUse, stats.TotalAlloc, stats.TotalFree, rtml.IsMemLimitReached())
+}
+
+// reportRSS prints the memory the container is charged for when the test ends.
+// The final value is the cgroup memory.current (memory.usage_in_bytes on cgroup v1),
+// falling back to VmRSS from /proc/self/status. The peak is the VmHWM high water mark.
+func reportRSS() {
+	finalRSS, err := rtml.CgroupMemoryCurrent()
+	if err != nil {
+		finalRSS, err = readProcStatusBytes("VmRSS")
+	}
+	if err == nil {
+		fmt.Printf("%s=%d\n", finalRSSMarker, finalRSS)
+	}
+
+	if peakRSS, err := readProcStatusBytes("VmHWM"); err == nil {
+		fmt.Printf("%s=%d\n", peakRSSMarker, peakRSS)
+	}
+}
+
+// readProcStatusBytes reads a memory field (reported in kB) from /proc/self/status
+func readProcStatusBytes(field string) (uint64, error) {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		value, found := strings.CutPrefix(line, field+":")
+		if !found {
+			continue
+		}
+		kb, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", field, err)
+		}
+		return kb * 1024, nil
+	}
+	return 0, fmt.Errorf("%s not found in /proc/self/status", field)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// startSampler prints an RTML_SAMPLE line every interval until the returned function is called,
+// which prints a last sample and waits for the sampler to stop.
+// The steady cadence, unlike the progress logs every few chunks, keeps the time series
+// comparable across allocation patterns and chunk sizes.
+func startSampler(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	reportSample(rtml.GetMemLimitRelatedStats())
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				reportSample(rtml.GetMemLimitRelatedStats())
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		reportSample(rtml.GetMemLimitRelatedStats())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	rtml "github.com/odigos-io/go-rtml"
+)
+
+// Structured line with the soak results, parsed by the test framework
+const soakMarker = "RTML_SOAK"
+
+const (
+	soakChunkSize      = 256 * 1024
+	soakSampleInterval = time.Millisecond
+
+	// compare with runtime/metrics every this many samples, it is too slow for every sample
+	soakGroundTruthEvery = 100
+)
+
+// SoakTest holds memory just below the limit, and keeps the GC active by replacing chunks
+type SoakTest struct {
+	duration          time.Duration
+	livePercent       uint64 // SOAK_LIVE_PERCENT, of the memory limit
+	maxFlipsPerSecond float64
+	minReachedPercent float64
+}
+
+func parseSoakTest() SoakTest {
+	return SoakTest{
+		duration:          time.Duration(getEnvAsIntOrDefault("SOAK_SECONDS", 30)) * time.Second,
+		livePercent:       uint64(getEnvAsIntOrDefault("SOAK_LIVE_PERCENT", 95)),
+		maxFlipsPerSecond: float64(getEnvAsIntOrDefault("SOAK_MAX_FLIPS_PER_SEC", 10)),
+		minReachedPercent: float64(getEnvAsIntOrDefault("SOAK_MIN_REACHED_PERCENT", 50)),
+	}
+}
+
+// runSoakTest fills the heap to SOAK_LIVE_PERCENT of the memory limit, then replaces chunks continuously
+// for SOAK_SECONDS while sampling IsMemLimitReached every millisecond.
+// Under steady pressure the result is expected to be stable: mostly true, and not flapping.
+func runSoakTest(soak SoakTest) {
+	limit := rtml.GetMemLimitRelatedStats().MemoryLimit
+	if limit == 0 || limit == math.MaxInt64 {
+		failf("the soak test requires GOMEMLIMIT to be set")
+	}
+
+	liveBytes := limit * soak.livePercent / 100
+	log.Printf("Running soak test for %v, holding %d MB live (%d%% of the %d MB limit)...",
+		soak.duration, bytesToMB(liveBytes), soak.livePercent, bytesToMB(limit))
+	fillChunks(liveBytes, soakChunkSize, false)
+	if len(globalChunks) == 0 {
+		failf("nothing to hold, the memory limit is below a single chunk")
+	}
+
+	done := make(chan struct{})
+	samples := make(chan soakSamples)
+	go sampleSoak(done, samples)
+
+	deadline := time.Now().Add(soak.duration)
+	replaced := uint64(0)
+	for time.Now().Before(deadline) {
+		index := replaced % uint64(len(globalChunks))
+		globalChunks[index] = allocateChunk(replaced, soakChunkSize)
+		replaced++
+	}
+	close(done)
+	result := <-samples
+
+	flipsPerSecond := float64(result.flips) / soak.duration.Seconds()
+	reachedPercent := float64(result.reached) * 100 / float64(max(result.total, 1))
+	log.Printf("Replaced %d MB while soaking", bytesToMB(replaced*soakChunkSize))
+	log.Printf("IsMemLimitReached sampled %d times: true %.1f%% of the time, flipped %d times (%.2f per second)",
+		result.total, reachedPercent, result.flips, flipsPerSecond)
+	log.Printf("Compared with runtime/metrics %d times: max discrepancy %.4f%% (%s), decision mismatches %d",
+		result.comparisons, result.maxDiscrepancy*100, result.maxDiscrepancyField, result.decisionMismatches)
+	fmt.Printf("%s Samples=%d Reached=%d Flips=%d DurationSeconds=%.0f MaxDiscrepancy=%g MaxDiscrepancyField=%s DecisionMismatches=%d\n",
+		soakMarker, result.total, result.reached, result.flips, soak.duration.Seconds(),
+		result.maxDiscrepancy, result.maxDiscrepancyField, result.decisionMismatches)
+
+	if reachedPercent < soak.minReachedPercent {
+		failf("IsMemLimitReached was true %.1f%% of the time under sustained pressure, expected at least %.0f%%",
+			reachedPercent, soak.minReachedPercent)
+	}
+	if flipsPerSecond > soak.maxFlipsPerSecond {
+		failf("IsMemLimitReached flipped %.2f times per second, expected at most %.0f",
+			flipsPerSecond, soak.maxFlipsPerSecond)
+	}
+	log.Printf("✅ IsMemLimitReached was stable under sustained pressure")
+}
+
+type soakSamples struct {
+	total, reached, flips uint64
+
+	// rtml.CompareWithRuntimeMetrics results, every soakGroundTruthEvery samples
+	comparisons         uint64
+	maxDiscrepancy      float64
+	maxDiscrepancyField string
+	decisionMismatches  uint64 // IsMemLimitReached disagreed with the decision made from runtime/metrics
+}
+
+// sampleSoak samples IsMemLimitReached until done is closed, then sends the counts.
+// It also measures how far the cheap path drifts from runtime/metrics under the sustained pressure.
+func sampleSoak(done <-chan struct{}, result chan<- soakSamples) {
+	ticker := time.NewTicker(soakSampleInterval)
+	defer ticker.Stop()
+
+	var samples soakSamples
+	previous := rtml.IsMemLimitReached()
+	for {
+		select {
+		case <-done:
+			result <- samples
+			return
+		case <-ticker.C:
+		}
+
+		reached := rtml.IsMemLimitReached()
+		samples.total++
+		if reached {
+			samples.reached++
+		}
+		if reached != previous {
+			samples.flips++
+		}
+		previous = reached
+
+		if samples.total%soakGroundTruthEvery == 0 {
+			comparison := rtml.CompareWithRuntimeMetrics()
+			samples.comparisons++
+			if comparison.MaxRelative > samples.maxDiscrepancy {
+				samples.maxDiscrepancy = comparison.MaxRelative
+				samples.maxDiscrepancyField = comparison.MaxField
+			}
+			if comparison.Reached != comparison.MetricsReached {
+				samples.decisionMismatches++
+			}
+		}
+	}
+}
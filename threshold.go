@@ -0,0 +1,152 @@
+package rtml
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// how often the registered thresholds are evaluated.
+const thresholdInterval = 100 * time.Millisecond
+
+// ThresholdHandle is returned by RegisterThreshold, and removes the threshold with Unregister.
+type ThresholdHandle struct {
+	ratio    float64
+	callback func(crossed bool)
+	source   StatsSource
+
+	// only accessed by the evaluation loop.
+	crossed bool
+}
+
+// all the registered thresholds, evaluated by a single loop that runs while there are any.
+var thresholds struct {
+	mu      sync.Mutex
+	handles []*ThresholdHandle
+	cancel  context.CancelFunc
+}
+
+// RegisterThreshold calls callback whenever LimitRatio crosses ratio:
+// with true once it goes at or above ratio, and with false once it goes back below it.
+// Different subsystems can react at different utilization levels, e.g. evict caches at 0.7
+// and stop accepting work at 0.9, without each of them running its own polling goroutine:
+//
+//	handle, err := rtml.RegisterThreshold(0.7, func(crossed bool) {
+//		cache.SetEvictAggressively(crossed)
+//	})
+//	defer handle.Unregister()
+//
+// All thresholds are evaluated by a single background loop, every 100ms, started with the first registration
+// and stopped when the last one is unregistered. Callbacks are only invoked on crossings, one at a time from
+// that loop, so they should return quickly. A ratio that is already crossed when registering is reported on the next tick.
+//
+// An error is returned if ratio is not positive.
+func RegisterThreshold(ratio float64, callback func(crossed bool)) (*ThresholdHandle, error) {
+	return RegisterThresholdFor(ratio, callback, runtimeStatsSource{})
+}
+
+// RegisterThresholdFor is RegisterThreshold, for a threshold on the LimitRatio of source instead of the go runtime.
+// It is evaluated by the same background loop. See StatsSource.
+func RegisterThresholdFor(ratio float64, callback func(crossed bool), source StatsSource) (*ThresholdHandle, error) {
+	if math.IsNaN(ratio) || ratio <= 0 {
+		return nil, fmt.Errorf("rtml: invalid threshold ratio %v, expected a positive ratio", ratio)
+	}
+	if callback == nil {
+		return nil, fmt.Errorf("rtml: threshold callback is nil")
+	}
+
+	handle := &ThresholdHandle{ratio: ratio, callback: callback, source: sourceOrRuntime(source)}
+
+	thresholds.mu.Lock()
+	defer thresholds.mu.Unlock()
+	thresholds.handles = append(thresholds.handles, handle)
+	if thresholds.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		thresholds.cancel = cancel
+		go runThresholds(ctx)
+	}
+	return handle, nil
+}
+
+// Unregister removes the threshold, and stops the background loop if it was the last one.
+// If the loop is evaluating the threshold at the same moment, the callback may still be invoked once.
+// It is safe to call more than once, and from within the callback.
+func (h *ThresholdHandle) Unregister() {
+	thresholds.mu.Lock()
+	defer thresholds.mu.Unlock()
+
+	for i, handle := range thresholds.handles {
+		if handle == h {
+			thresholds.handles = append(thresholds.handles[:i:i], thresholds.handles[i+1:]...)
+			break
+		}
+	}
+	if len(thresholds.handles) == 0 && thresholds.cancel != nil {
+		thresholds.cancel()
+		thresholds.cancel = nil
+	}
+}
+
+// Ratio returns the ratio the threshold was registered with.
+func (h *ThresholdHandle) Ratio() float64 {
+	return h.ratio
+}
+
+func runThresholds(ctx context.Context) {
+	ticker := time.NewTicker(thresholdInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		evaluateThresholds(ctx)
+	}
+}
+
+// invokes the callbacks of the thresholds that the ratio of their source crossed since the last evaluation.
+// the runtime ratio is read once per evaluation, so all the runtime thresholds see the same value.
+func evaluateThresholds(ctx context.Context) {
+	thresholds.mu.Lock()
+	handles := append([]*ThresholdHandle(nil), thresholds.handles...)
+	thresholds.mu.Unlock()
+
+	runtimeRatio := math.NaN()
+	for _, handle := range handles {
+		var ratio float64
+		if _, ok := handle.source.(runtimeStatsSource); ok {
+			if math.IsNaN(runtimeRatio) {
+				runtimeRatio = LimitRatio()
+			}
+			ratio = runtimeRatio
+		} else {
+			ratio = utilizationRatioOf(handle.source)
+		}
+
+		crossed := ratio >= handle.ratio
+		if crossed == handle.crossed {
+			continue
+		}
+		// skip the handles unregistered since the copy was taken (e.g. by a previous callback).
+		if ctx.Err() != nil || !thresholdRegistered(handle) {
+			continue
+		}
+		handle.crossed = crossed
+		handle.callback(crossed)
+	}
+}
+
+func thresholdRegistered(h *ThresholdHandle) bool {
+	thresholds.mu.Lock()
+	defer thresholds.mu.Unlock()
+	for _, handle := range thresholds.handles {
+		if handle == h {
+			return true
+		}
+	}
+	return false
+}
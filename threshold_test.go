@@ -0,0 +1,73 @@
+package rtml
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a StatsSource whose memory in use can be moved while the threshold loop reads it.
+type movingSource struct {
+	mappedReady atomic.Uint64
+}
+
+func (s *movingSource) Stats() MemLimitRelatedStats {
+	return MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: s.mappedReady.Load()}
+}
+
+func (s *movingSource) Reached() bool { return false }
+
+func TestRegisterThresholdFor(t *testing.T) {
+	source := &movingSource{}
+	source.mappedReady.Store(500)
+	crossings := make(chan bool, 10)
+	handle, err := RegisterThresholdFor(0.7, func(crossed bool) { crossings <- crossed }, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Unregister()
+
+	expect := func(want bool) {
+		t.Helper()
+		select {
+		case crossed := <-crossings:
+			if crossed != want {
+				t.Fatalf("callback(%t), want callback(%t)", crossed, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no callback(%t) within a second", want)
+		}
+	}
+	source.mappedReady.Store(700)
+	expect(true)
+	source.mappedReady.Store(900)
+	source.mappedReady.Store(699)
+	expect(false)
+
+	// no crossing, no callback.
+	select {
+	case crossed := <-crossings:
+		t.Errorf("callback(%t) without a crossing", crossed)
+	case <-time.After(3 * thresholdInterval):
+	}
+
+	handle.Unregister()
+	thresholds.mu.Lock()
+	running := thresholds.cancel != nil
+	thresholds.mu.Unlock()
+	if running {
+		t.Error("the threshold loop still runs after the last threshold was unregistered")
+	}
+}
+
+func TestRegisterThresholdInvalid(t *testing.T) {
+	for _, ratio := range []float64{0, -0.5, math.NaN()} {
+		if _, err := RegisterThreshold(ratio, func(bool) {}); err == nil {
+			t.Errorf("RegisterThreshold(%v) accepted an invalid ratio", ratio)
+		}
+	}
+	if _, err := RegisterThreshold(0.5, nil); err == nil {
+		t.Error("RegisterThreshold accepted a nil callback")
+	}
+}
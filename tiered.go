@@ -0,0 +1,63 @@
+package rtml
+
+import "math"
+
+// IsMemLimitReachedAt reports whether memory reached ratio of the memory limit.
+// It is the primitive for tiered admission, where work of different cost is rejected at different levels:
+//
+//	func admit(expensive bool) bool {
+//		if expensive {
+//			return !rtml.IsMemLimitReachedAt(0.8) // shed expensive work first, from 80% of the limit
+//		}
+//		return !rtml.IsMemLimitReachedAt(0.95) // keep serving cheap work until 95%
+//	}
+//
+// ratio is clamped to [0, 1] (NaN is treated as 1). The decision is the one of IsMemLimitReached,
+// with the limit (after the headroom of WithHeadroomFraction) scaled by ratio, so at 1 it is exactly IsMemLimitReached.
+// Below 1 the scaled limit leaves a headroom below the memory limit, so like with WithHeadroomFraction,
+// the limit is reached as soon as the memory in use (MappedReady - HeapFree) reaches it, without the heap goal check,
+// which only applies at the limit itself. A lower ratio is therefore never reached later than a higher one.
+// While the runtime values can't be trusted (ReasonDegraded, ReasonInconsistent), the result follows SetConservative
+// at every ratio, once the mapped memory reached the scaled limit.
+//
+// It is as cheap as IsMemLimitReached: below the tier, it costs two atomic loads and a compare.
+// When no memory limit is set, it always returns false.
+func IsMemLimitReachedAt(ratio float64) bool {
+	memoryLimit := runtimeGCController.memoryLimit.Load()
+	mappedReady := runtimeGCController.mappedReady.Load()
+
+	// fast check, as in IsMemLimitReached: below the tier even before deducting the free heap.
+	effectiveLimit := tierLimit(uint64(memoryLimit), ratio)
+	if effectiveLimit > mappedReady {
+		return false
+	}
+	return reachedForReason(memLimitSlowPathReason(memoryLimit, effectiveLimit, mappedReady))
+}
+
+// IsMemLimitReachedAt for source, see StatsSource.
+func memLimitReachedAtOf(source StatsSource, ratio float64) bool {
+	if _, ok := source.(runtimeStatsSource); ok {
+		return IsMemLimitReachedAt(ratio)
+	}
+	if clampRatio(ratio) >= 1 {
+		return source.Reached()
+	}
+	s := source.Stats()
+	return reachedForReason(memLimitReason(snapshotInputs(s, tierLimit(s.MemoryLimit, ratio))))
+}
+
+// the limit IsMemLimitReachedAt compares with: the effective memory limit, scaled by ratio.
+func tierLimit(memoryLimit uint64, ratio float64) uint64 {
+	limit := effectiveMemoryLimit(memoryLimit)
+	if ratio = clampRatio(ratio); ratio < 1 {
+		limit = uint64(float64(limit) * ratio)
+	}
+	return limit
+}
+
+func clampRatio(ratio float64) float64 {
+	if math.IsNaN(ratio) {
+		return 1
+	}
+	return min(max(ratio, 0), 1)
+}
@@ -0,0 +1,79 @@
+package rtml
+
+import "testing"
+
+func TestIsMemLimitReachedAtIsMonotonic(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    MemLimitRelatedStats
+		headroom float64
+	}{
+		{
+			name:  "below the limit",
+			stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 700, HeapFree: 100, HeapGoal: 900, HeapLive: 500},
+		},
+		{
+			name:  "above the limit, below the goal",
+			stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1100, HeapFree: 50, HeapGoal: 900, HeapLive: 800},
+		},
+		{
+			name:  "above the limit, above the goal",
+			stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1100, HeapFree: 50, HeapGoal: 700, HeapLive: 800},
+		},
+		{
+			name:  "above the limit, warming up",
+			stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1100, HeapFree: 50},
+		},
+		{
+			name: "within the headroom",
+			stats: MemLimitRelatedStats{MemoryLimit: 1_000_000_000, MappedReady: 980_000_000, HeapFree: 20_000_000,
+				HeapGoal: 900_000_000, HeapLive: 800_000_000},
+			headroom: 0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHeadroomForTest(t, tt.headroom)
+
+			source := &fakeSource{stats: tt.stats}
+			source.reached.Store(MemLimitReachedFor(tt.stats))
+
+			// reached at a ratio means reached at every lower ratio.
+			previous := true
+			for i := 0; i <= 100; i++ {
+				ratio := float64(i) / 100
+				reached := memLimitReachedAtOf(source, ratio)
+				if reached && !previous {
+					t.Fatalf("reached at %.2f, but not at %.2f", ratio, ratio-0.01)
+				}
+				previous = reached
+			}
+			if at, want := memLimitReachedAtOf(source, 1), MemLimitReachedFor(tt.stats); at != want {
+				t.Errorf("at 1 = %t, want %t as MemLimitReachedFor", at, want)
+			}
+		})
+	}
+}
+
+func TestIsMemLimitReachedAtBelowOneSkipsGoal(t *testing.T) {
+	// above the limit, below the goal: not reached at the limit itself, reached at any tier below it.
+	stats := MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 1100, HeapFree: 50, HeapGoal: 900, HeapLive: 800}
+	source := &fakeSource{stats: stats}
+	if memLimitReachedAtOf(source, 1) {
+		t.Error("reached at 1 while the live heap is below the goal")
+	}
+	if !memLimitReachedAtOf(source, 0.99) {
+		t.Error("not reached at 0.99 while the memory in use is above the limit")
+	}
+}
+
+func TestIsMemLimitReachedAtAgreesWithLive(t *testing.T) {
+	Warmup()
+	setMemoryLimitForTest(t, int64(GetMemLimitRelatedStats().MappedReady)+64<<20)
+	if at, reached := IsMemLimitReachedAt(1), IsMemLimitReached(); at != reached {
+		t.Errorf("IsMemLimitReachedAt(1) = %t, while IsMemLimitReached() = %t", at, reached)
+	}
+	if ratio := LimitRatio(); ratio > 0 && !IsMemLimitReachedAt(ratio/2) {
+		t.Errorf("IsMemLimitReachedAt(%.3f) = false while the limit ratio is %.3f", ratio/2, ratio)
+	}
+}
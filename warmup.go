@@ -0,0 +1,63 @@
+package rtml
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// set once the garbage collector controller was observed with a non-zero heap goal and live heap.
+// warming up only happens once, on process start, so it is latched and never reset.
+var controllerWarmedUp atomic.Bool
+
+// ControllerReady reports whether the garbage collector controller looks initialized,
+// i.e. both the heap goal and the live heap are non-zero.
+//
+// At process start, before the runtime updated them for the first time, some of the controller values
+// can read as zero, and the heuristic can't tell a real memory problem from an uninitialized state.
+// Until the controller is ready, IsMemLimitReached returns "false" (with ReasonWarmingUp),
+// regardless of SetConservative, to avoid spurious rejections during startup.
+//
+// Once it returned true it keeps returning true, even if the values later read as zero.
+func ControllerReady() bool {
+	if controllerWarmedUp.Load() {
+		return true
+	}
+	if degraded.Load() {
+		return false
+	}
+
+	heapGoal, ok := readHeapGoal()
+	if !ok {
+		return false
+	}
+	return observeControllerWarmUp(heapGoal, runtimeGCController.heapLive.Load())
+}
+
+// records the warm up once heapGoal and heapLive are both set, and reports if it happened (now or before).
+func observeControllerWarmUp(heapGoal uint64, heapLive uint64) bool {
+	if controllerWarmedUp.Load() {
+		return true
+	}
+	if heapGoal == 0 || heapLive == 0 {
+		return false
+	}
+	controllerWarmedUp.Store(true)
+	return true
+}
+
+// upper bound of the collections Warmup triggers, in case the controller never looks ready (e.g. degraded).
+const warmupMaxGCs = 3
+
+// Warmup triggers a garbage collection, so the controller values are populated and ControllerReady returns true,
+// for processes that need accurate admission from their first request (instead of IsMemLimitReached
+// returning false with ReasonWarmingUp until the runtime gets there on its own).
+//
+// It is optional, and meant to be called once at startup, before any work is accepted.
+// It blocks for a full, stop the world, collection (usually milliseconds, proportional to the live heap),
+// and returns immediately when the controller is already ready. Check ControllerReady after it returns:
+// when the runtime values can't be read, it gives up after a few collections instead of blocking forever.
+func Warmup() {
+	for i := 0; i < warmupMaxGCs && !ControllerReady() && !degraded.Load(); i++ {
+		runtime.GC()
+	}
+}
@@ -0,0 +1,30 @@
+package rtml
+
+import "testing"
+
+func TestObserveControllerWarmUp(t *testing.T) {
+	// simulate a process that didn't warm up yet, the latch is global.
+	previous := controllerWarmedUp.Load()
+	controllerWarmedUp.Store(false)
+	t.Cleanup(func() { controllerWarmedUp.Store(previous) })
+
+	for _, values := range [][2]uint64{{0, 0}, {1, 0}, {0, 1}} {
+		if observeControllerWarmUp(values[0], values[1]) {
+			t.Errorf("observeControllerWarmUp(heapGoal=%d, heapLive=%d) = true, want false", values[0], values[1])
+		}
+	}
+	if !observeControllerWarmUp(1, 1) {
+		t.Fatalf("observeControllerWarmUp(1, 1) = false, want true")
+	}
+	// latched, later zero values don't undo the warm up.
+	if !observeControllerWarmUp(0, 0) {
+		t.Errorf("observeControllerWarmUp(0, 0) = false after warming up, want true")
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	Warmup()
+	if !ControllerReady() {
+		t.Fatalf("ControllerReady() = false after Warmup")
+	}
+}
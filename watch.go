@@ -0,0 +1,94 @@
+package rtml
+
+import (
+	"context"
+	"time"
+)
+
+// capacity of the channel returned by WatchMemLimitStats.
+const watchBufferSize = 16
+
+// WatchOption customizes WatchMemLimitStats.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	heartbeat time.Duration
+	source    StatsSource
+}
+
+// WithHeartbeat also emits a snapshot when heartbeat elapsed since the last one,
+// even if the reached state did not change, e.g. to feed periodic metrics.
+func WithHeartbeat(heartbeat time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.heartbeat = heartbeat
+	}
+}
+
+// WithWatchStatsSource reads the memory limit state from source instead of the go runtime,
+// e.g. to test the consumers of the channel with scripted values. See StatsSource.
+func WithWatchStatsSource(source StatsSource) WatchOption {
+	return func(c *watchConfig) {
+		c.source = source
+	}
+}
+
+// WatchMemLimitStats checks IsMemLimitReached every interval (1 second when interval is not positive), and emits a stats snapshot on the returned channel
+// whenever the result changes, so consumers can log or emit metrics with the numbers behind each transition.
+// The first check always emits, and WithHeartbeat adds periodic snapshots in between transitions.
+//
+// The channel is buffered (16 snapshots). When a slow consumer lets it fill up, the oldest snapshot
+// is dropped to make room, so the most recent state is never lost. The channel is closed once ctx is done.
+func WatchMemLimitStats(ctx context.Context, interval time.Duration, opts ...WatchOption) <-chan MemLimitRelatedStats {
+	var config watchConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	source := sourceOrRuntime(config.source)
+
+	ch := make(chan MemLimitRelatedStats, watchBufferSize)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(pollInterval(interval))
+		defer ticker.Stop()
+
+		first := true
+		var reached bool
+		var lastEmit time.Time
+		for {
+			current := source.Reached()
+			now := time.Now()
+			heartbeat := config.heartbeat > 0 && now.Sub(lastEmit) >= config.heartbeat
+			if first || current != reached || heartbeat {
+				emitDroppingOldest(ch, source.Stats())
+				lastEmit = now
+			}
+			first = false
+			reached = current
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
+
+// sends stats on ch, dropping the oldest buffered value if ch is full.
+// ch must only be sent on from the calling goroutine.
+func emitDroppingOldest(ch chan MemLimitRelatedStats, stats MemLimitRelatedStats) {
+	select {
+	case ch <- stats:
+		return
+	default:
+	}
+
+	select {
+	case <-ch:
+	default:
+		// the consumer drained it in the meantime.
+	}
+	ch <- stats
+}
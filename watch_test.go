@@ -0,0 +1,25 @@
+package rtml
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchMemLimitStatsNonPositiveInterval(t *testing.T) {
+	source := &fakeSource{stats: MemLimitRelatedStats{MemoryLimit: 1000, MappedReady: 500}}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := WatchMemLimitStats(ctx, 0, WithWatchStatsSource(source))
+
+	select {
+	case stats := <-ch:
+		if stats != source.stats {
+			t.Errorf("first snapshot = %s, want %s", stats, source.stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no first snapshot")
+	}
+	cancel()
+	for range ch {
+	}
+}